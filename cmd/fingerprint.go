@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"xiaoyu/pkg/fingerprint"
+)
+
+// frameworks 内置的产品指纹，即 config.yaml 中的 framework 列表
+var frameworks []byte
+
+// SetFrameworks
+// @Description: 设置内置的产品指纹数据
+// @param data
+func SetFrameworks(data []byte) {
+	frameworks = data
+}
+
+// loadFingerprints
+// @Description: 加载内置产品指纹，再叠加用户指定的文件或目录，同名产品以用户定义为准
+// @param path
+// @return *fingerprint.DB
+// @return error
+func loadFingerprints(path string) (*fingerprint.DB, error) {
+	db, err := fingerprint.ParseDB(frameworks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse built-in fingerprints: %w", err)
+	}
+	if path != "" {
+		if err := db.Load(path); err != nil {
+			return nil, err
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"file":     path,
+		"products": len(db.Products),
+	}).Debug("Product fingerprints loaded")
+	return db, nil
+}
@@ -4,12 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	log "github.com/sirupsen/logrus"
-	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"xiaoyu/pkg/browser"
+	"xiaoyu/pkg/config"
+	"xiaoyu/pkg/creds"
 )
 
 var gCtx = context.Background()
@@ -19,32 +24,9 @@ var rootCmd = &cobra.Command{
 	Long:  "A tool for testing web login pages with automatic detection and manual configuration support",
 }
 
-type Options struct {
-	inputs            []string
-	inputsFile        string
-	logLevel          string
-	outputFile        string
-	crackAll          bool
-	delay             int
-	headless          bool
-	detectOnly        bool
-	maxAttempts       int
-	maxCrackNum       int
-	maxCrackTime      int
-	loginTimeout      int
-	elementTimeout    int
-	navigationTimeout int
-	passList          []string
-	passFile          string
-	proxy             string
-	selectorFile      string
-	threads           int
-	userList          []string
-	userFile          string
-	ocrURL            string
-}
-
-var globalOptions = &Options{}
+// globalConfig 命令行参数直接绑定到该配置上，resolveConfig 会在其基础上合并配置文件与环境变量
+var globalConfig = config.NewConfig()
+var configFile string
 
 func setupLogging(level string) error {
 	parsedLevel, err := log.ParseLevel(level)
@@ -55,100 +37,367 @@ func setupLogging(level string) error {
 	return nil
 }
 
-func loadConfig(flags *Options) (*Options, error) {
-	if flags.inputsFile != "" {
-		data, err := os.ReadFile(flags.inputsFile)
+// resolveConfig
+// @Description: 按 配置文件 < 环境变量 < 命令行参数 的优先级生成最终配置
+// @param cmd
+// @return error
+func resolveConfig(cmd *cobra.Command) error {
+	// 记录用户显式传入的参数
+	type changedFlag struct {
+		flag  *pflag.Flag
+		value string
+		slice []string
+	}
+	var changed []changedFlag
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		c := changedFlag{flag: f}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			c.slice = sv.GetSlice()
+		} else {
+			c.value = f.Value.String()
+		}
+		changed = append(changed, c)
+	})
+
+	cfg := config.NewConfig()
+	if configFile != "" {
+		if err := cfg.LoadFile(configFile); err != nil {
+			return err
+		}
+	}
+	if err := cfg.ApplyEnv(os.LookupEnv); err != nil {
+		return err
+	}
+
+	// 参数绑定的是 globalConfig 的字段地址，这里整体替换后再回放命令行参数
+	*globalConfig = *cfg
+	for _, c := range changed {
+		if sv, ok := c.flag.Value.(pflag.SliceValue); ok {
+			if err := sv.Replace(c.slice); err != nil {
+				return fmt.Errorf("invalid value for --%s: %w", c.flag.Name, err)
+			}
+			continue
+		}
+		if err := c.flag.Value.Set(c.value); err != nil {
+			return fmt.Errorf("invalid value for --%s: %w", c.flag.Name, err)
+		}
+	}
+	return nil
+}
+
+// loadList
+// @Description: 读取列表文件并输出统计
+// @param kind 列表类型，用于日志和错误信息
+// @param path
+// @return []string
+// @return error
+func loadList(kind, path string) ([]string, error) {
+	list, stats, err := creds.LoadList(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s file: %w", kind, err)
+	}
+	logStats(kind, stats)
+	return list, nil
+}
+
+// loadPairs
+// @Description: 读取凭证组合文件并输出统计
+// @param path
+// @return []creds.Pair
+// @return error
+func loadPairs(path string) ([]creds.Pair, error) {
+	pairs, stats, err := creds.LoadPairs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read combo file: %w", err)
+	}
+	logStats("combo", stats)
+	return pairs, nil
+}
+
+func logStats(kind string, stats creds.Stats) {
+	entry := log.WithFields(log.Fields{
+		"kind":       kind,
+		"file":       stats.Source,
+		"kept":       stats.Kept,
+		"blank":      stats.Blank,
+		"comments":   stats.Comments,
+		"duplicates": stats.Duplicates,
+		"invalid":    stats.Invalid,
+	})
+	if stats.Invalid > 0 {
+		entry.Warn("Credential file loaded with invalid lines")
+		return
+	}
+	entry.Info("Credential file loaded")
+}
+
+// checkStdin
+// @Description: 标准输入只能被一个文件参数使用
+// @param flags
+// @param targets
+// @return error
+func checkStdin(flags *config.Config, targets []*config.Target) error {
+	var users []string
+	add := func(name, path string) {
+		if path == creds.Stdin {
+			users = append(users, name)
+		}
+	}
+	add("inputsFile", flags.InputsFile)
+	add("userFile", flags.UserFile)
+	add("passFile", flags.PassFile)
+	add("comboFile", flags.ComboFile)
+	for _, t := range targets {
+		add(t.URL+" userFile", t.UserFile)
+		add(t.URL+" passFile", t.PassFile)
+		add(t.URL+" comboFile", t.ComboFile)
+	}
+	if len(users) > 1 {
+		return fmt.Errorf("stdin (-) can only be read once, used by %s", strings.Join(users, ", "))
+	}
+	return nil
+}
+
+func loadConfig(flags *config.Config) (*config.Config, error) {
+	var loaded []*config.Target
+	if flags.TargetsFile != "" {
+		var err error
+		if loaded, err = config.LoadTargets(flags.TargetsFile); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkStdin(flags, append(append([]*config.Target{}, flags.Targets...), loaded...)); err != nil {
+		return nil, err
+	}
+
+	if flags.InputsFile != "" {
+		lines, err := loadList("inputs", flags.InputsFile)
+		if err != nil {
+			return nil, err
+		}
+		flags.Inputs = append(flags.Inputs, lines...)
+	}
+
+	if flags.UserFile != "" {
+		lines, err := loadList("user", flags.UserFile)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read inputs file: %w", err)
+			return nil, err
 		}
-		flags.inputs = append(flags.inputs, strings.Split(string(data), "\n")...)
+		flags.UserList = append(flags.UserList, lines...)
 	}
+	flags.UserList = creds.Dedupe(flags.UserList)
 
-	if flags.userFile != "" {
-		data, err := os.ReadFile(flags.userFile)
+	if flags.PassFile != "" {
+		lines, err := loadList("password", flags.PassFile)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read user file: %w", err)
+			return nil, err
 		}
-		flags.userList = append(flags.userList, strings.Split(string(data), "\n")...)
+		flags.PassList = append(flags.PassList, lines...)
 	}
+	flags.PassList = creds.Dedupe(flags.PassList)
 
-	if flags.passFile != "" {
-		data, err := os.ReadFile(flags.passFile)
+	if flags.ComboFile != "" {
+		pairs, err := loadPairs(flags.ComboFile)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read password file: %w", err)
+			return nil, err
 		}
-		flags.passList = append(flags.passList, strings.Split(string(data), "\n")...)
+		flags.Combos = pairs
+	}
+
+	// 组装目标：--inputs 中的URL使用全局配置，清单中的目标可单独覆盖
+	var targets []*config.Target
+	for _, url := range flags.Inputs {
+		if strings.TrimSpace(url) == "" {
+			continue
+		}
+		targets = append(targets, &config.Target{URL: url})
+	}
+	targets = append(targets, flags.Targets...)
+	targets = append(targets, loaded...)
+
+	// 表单序号因页面而异，全局配置只能用于单个目标
+	if flags.FormIndex != config.DefaultFormIndex && len(targets) > 1 {
+		return nil, fmt.Errorf("--form-index applies to a single target, set formIndex per target in --targets-file instead")
+	}
+
+	// 选择器文件在启动时统一校验，同一文件只加载一次
+	selectors := make(map[string]*browser.Selector)
+	for _, t := range targets {
+		t.Inherit(flags)
+
+		if t.SelectorFile != "" {
+			s, ok := selectors[t.SelectorFile]
+			if !ok {
+				var err error
+				if s, err = loadSelectorFile(t.SelectorFile); err != nil {
+					return nil, err
+				}
+				selectors[t.SelectorFile] = s
+			}
+			t.Selector = s
+		}
+
+		if t.UserFile != "" {
+			lines, err := loadList("user", t.UserFile)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", t.URL, err)
+			}
+			t.Users = append(t.Users, lines...)
+		}
+		t.Users = creds.Dedupe(t.Users)
+
+		if t.PassFile != "" {
+			lines, err := loadList("password", t.PassFile)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", t.URL, err)
+			}
+			t.Passwords = append(t.Passwords, lines...)
+		}
+		t.Passwords = creds.Dedupe(t.Passwords)
+
+		if t.ComboFile != "" {
+			pairs, err := loadPairs(t.ComboFile)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", t.URL, err)
+			}
+			t.Combos = pairs
+		}
+
+		reportCredentials(flags, t)
 	}
+	flags.Targets = targets
 
-	if len(flags.inputs) == 0 {
+	if len(flags.Targets) == 0 {
 		return nil, fmt.Errorf("no input URLs provided")
 	}
 
 	return flags, nil
 }
 
-func printConfiguration(flags *Options) {
-	config := map[string]interface{}{
-		"headlessOptions": map[string]interface{}{
-			"headless":    flags.headless,
-			"debug":       false,
-			"maxAttempts": flags.maxAttempts,
-			"ocrBaseURL":  flags.ocrURL,
-		},
-		"threads":      flags.threads,
-		"crackAll":     flags.crackAll,
-		"delay":        flags.delay,
-		"maxCrackTime": flags.maxCrackTime,
-	}
-
-	jsonBytes, _ := json.Marshal(config)
+// printConfiguration
+// @Description: 输出脱敏后的配置，用户名和密码只输出数量
+// @param flags
+func printConfiguration(flags *config.Config) {
+	jsonBytes, err := json.MarshalIndent(flags.Redacted(), "", "  ")
+	if err != nil {
+		log.WithError(err).Error("Failed to encode configuration")
+		return
+	}
 	log.WithFields(log.Fields{
-		"config": string(jsonBytes),
+		"config":    string(jsonBytes),
+		"users":     len(flags.UserList),
+		"passwords": len(flags.PassList),
+		"combos":    len(flags.Combos),
 	}).Info("Configuration loaded")
+}
 
-	if flags.selectorFile != "" {
-		data, _ := os.ReadFile(flags.selectorFile)
-		var s browser.Selector
-		_ = yaml.Unmarshal(data, &s)
-		selectorJSON, _ := json.MarshalIndent(s, "", "  ")
-		log.WithFields(log.Fields{
-			"selectors": string(selectorJSON),
-		}).Info("Login selectors detected")
+// reportCredentials
+// @Description: 输出目标的凭证数量，一一对应模式下提示无法配对而被丢弃的用户或密码
+// @param flags
+// @param t
+func reportCredentials(flags *config.Config, t *config.Target) {
+	log.WithFields(log.Fields{
+		"url":       t.URL,
+		"users":     len(t.Users),
+		"passwords": len(t.Passwords),
+		"combos":    len(t.Combos),
+	}).Info("Credentials ready")
+
+	// 未指定密码时使用默认凭证，无需配对
+	if len(t.Passwords) == 0 && len(t.Combos) == 0 {
+		log.WithField("url", t.URL).Info("No passwords given, default credentials will be used")
+		return
+	}
+	if flags.CrackAll || len(t.Users) == len(t.Passwords) {
+		return
 	}
+	// 只用组合文件时不提示
+	if len(t.Combos) > 0 && (len(t.Users) == 0 || len(t.Passwords) == 0) {
+		return
+	}
+	log.WithFields(log.Fields{
+		"url":       t.URL,
+		"users":     len(t.Users),
+		"passwords": len(t.Passwords),
+		"dropped":   absDiff(len(t.Users), len(t.Passwords)),
+	}).Warn("Users and passwords are paired line by line, unpaired entries are skipped; use --crack-all or --combo-file")
 }
 
-func saveResults(results interface{}, outputFile string) error {
-	file, err := os.Create(outputFile)
-	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+func absDiff(a, b int) int {
+	if a > b {
+		return a - b
 	}
-	defer file.Close()
+	return b - a
+}
 
-	if err := json.NewEncoder(file).Encode(results); err != nil {
-		return fmt.Errorf("failed to write results: %w", err)
+// loadSelectorFile
+// @Description: 严格加载选择器文件，格式错误和未知字段直接返回
+// @param path
+// @return *browser.Selector
+// @return error
+func loadSelectorFile(path string) (*browser.Selector, error) {
+	s, err := browser.LoadSelectorFile(path)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+
+	selectorJSON, _ := json.MarshalIndent(s, "", "  ")
+	log.WithFields(log.Fields{
+		"file":      path,
+		"selectors": string(selectorJSON),
+	}).Info("Login selectors loaded")
+	return s, nil
+}
+
+// handleSignals
+// @Description: 收到 SIGINT/SIGTERM 时取消全局上下文，让各worker关闭浏览器、写出已有结果后退出；
+// 再次收到信号时按默认行为直接结束进程
+// @return context.CancelFunc
+func handleSignals() context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	gCtx = ctx
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-sigCh:
+			log.WithField("signal", sig.String()).Warn("Interrupted, stopping workers and flushing results")
+			signal.Stop(sigCh)
+			cancel()
+		case <-ctx.Done():
+			signal.Stop(sigCh)
+		}
+	}()
+	return cancel
 }
 
 func Execute() {
+	cancel := handleSignals()
+	defer cancel()
+
 	rootFlags := rootCmd.PersistentFlags()
 
+	// Config file
+	rootFlags.StringVarP(&configFile, "config", "c", "", "run configuration file (yaml or json)")
+
 	// Browser automation flags
-	rootFlags.BoolVar(&globalOptions.headless, "headless", false, "headless mode")
-	rootFlags.StringVar(&globalOptions.proxy, "proxy", "", "proxy")
-	rootFlags.BoolVar(&globalOptions.detectOnly, "detect-only", false, "only detect form elements without login")
-	rootFlags.IntVar(&globalOptions.threads, "threads", 3, "scan threads")
+	rootFlags.BoolVar(&globalConfig.Headless, "headless", false, "headless mode")
+	rootFlags.StringVar(&globalConfig.Proxy, "proxy", "", "proxy")
+	rootFlags.BoolVar(&globalConfig.DetectOnly, "detect-only", false, "only detect form elements without login")
+	rootFlags.IntVar(&globalConfig.Threads, "threads", config.DefaultThreads, "scan threads")
+	rootFlags.IntVar(&globalConfig.HostThreads, "host-threads", config.DefaultHostThreads, "max concurrent workers per host, 0 is same as threads")
 
 	// Input flags
-	rootFlags.StringSliceVarP(&globalOptions.inputs, "inputs", "i", nil, "inputs split by comma")
-	rootFlags.StringVarP(&globalOptions.inputsFile, "inputs-file", "f", "", "inputs file split by line")
-	rootFlags.StringVarP(&globalOptions.outputFile, "output-file", "o", "output.json", "output file to write results")
-	rootFlags.StringVar(&globalOptions.logLevel, "level", "debug", "logger level(debug|info|error)")
-
-	// required
-	_ = rootCmd.MarkPersistentFlagRequired("inputs")
+	rootFlags.StringSliceVarP(&globalConfig.Inputs, "inputs", "i", nil, "inputs split by comma")
+	rootFlags.StringVarP(&globalConfig.InputsFile, "inputs-file", "f", "", "inputs file split by line")
+	rootFlags.StringVarP(&globalConfig.TargetsFile, "targets-file", "t", "", "targets manifest file (yaml or json) with per-target overrides")
+	rootFlags.StringVarP(&globalConfig.OutputFile, "output-file", "o", config.DefaultOutputFile, "output file to write results")
+	rootFlags.StringVar(&globalConfig.StreamFile, "stream-file", "", "jsonl file to stream results, default derived from output file")
+	rootFlags.StringVar(&globalConfig.LogLevel, "level", config.DefaultLogLevel, "logger level(debug|info|error)")
 
-	if err := rootCmd.Execute(); err != nil {
+	if err := rootCmd.ExecuteContext(gCtx); err != nil {
+		cancel()
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"xiaoyu/pkg/browser"
+	"xiaoyu/pkg/config"
+)
+
+func init() {
+	selectorsValidateCmd.Flags().StringVar(&globalConfig.SelectorFile, "selector-file", "", "selector file to validate")
+
+	selectorsCmd.AddCommand(selectorsValidateCmd)
+	selectorsCmd.AddCommand(selectorsSchemaCmd)
+	webLoginCmd.AddCommand(selectorsCmd)
+}
+
+var selectorsCmd = &cobra.Command{
+	Use:   "selectors",
+	Short: "Selector file utilities",
+}
+
+var selectorsValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate a selector file against the schema and live pages",
+	Long:  "Check a selector file against the selector schema with line-numbered errors. When URLs are given, load each page and report per field whether a candidate matches exactly one visible element.",
+	Example: `  # Only check the file
+  ./weblogin weblogin selectors validate --selector-file demo.yaml
+
+  # Check the file and the selectors on a live page
+  ./weblogin weblogin selectors validate -i http://example.com:9001 --selector-file demo.yaml`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := globalConfig.SelectorFile
+		if path == "" {
+			return fmt.Errorf("--selector-file is required")
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read selector file: %w", err)
+		}
+
+		failures := 0
+		for _, e := range browser.ValidateSelectorData(data) {
+			log.WithFields(log.Fields{
+				"file":  path,
+				"line":  e.Line,
+				"field": e.Field,
+			}).Error(e.Message)
+			failures++
+		}
+		if failures > 0 {
+			return fmt.Errorf("selector file %s has %d error(s)", path, failures)
+		}
+		log.WithField("file", path).Info("Selector file is valid")
+
+		// 未指定目标时只做文件校验
+		if len(globalConfig.Inputs) == 0 && globalConfig.InputsFile == "" && globalConfig.TargetsFile == "" && len(globalConfig.Targets) == 0 {
+			return nil
+		}
+
+		cfg, err := loadConfig(globalConfig)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		for _, t := range cfg.Targets {
+			if !validateOnPage(gCtx, t) {
+				failures++
+			}
+		}
+		if failures > 0 {
+			return fmt.Errorf("selectors did not match on %d target(s)", failures)
+		}
+		return nil
+	},
+}
+
+var selectorsSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema for selector files",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, err := os.Stdout.Write(browser.SelectorSchema)
+		return err
+	},
+}
+
+// validateOnPage
+// @Description: 打开目标页面并逐字段检查选择器，输出每个候选的匹配数量
+// @param ctx
+// @param t
+// @return bool 全部字段均恰好匹配一个可见元素
+func validateOnPage(ctx context.Context, t *config.Target) bool {
+	logger := log.WithField("url", t.URL)
+
+	if t.Selector == nil {
+		logger.Error("No selector configured for target")
+		return false
+	}
+
+	b, err := newBrowser(t)
+	if err != nil {
+		logger.WithError(err).Error("Failed to create browser")
+		return false
+	}
+	defer b.Close()
+
+	navigateCtx, cancel := context.WithTimeout(ctx, time.Duration(t.NavigationTimeout)*time.Second)
+	defer cancel()
+
+	if err = b.Navigate(navigateCtx, t.URL); err != nil {
+		logger.WithError(err).Error("Failed to navigate to URL")
+		return false
+	}
+
+	ok := true
+	for _, report := range b.ValidateSelector(ctx, t.Selector) {
+		for _, c := range report.Candidates {
+			entry := logger.WithFields(log.Fields{
+				"field":    report.Field,
+				"selector": c.Selector,
+				"matches":  c.Matches,
+				"visible":  c.Visible,
+			})
+			switch {
+			case c.Error != "":
+				entry.WithField("error", c.Error).Warn("Selector failed")
+			case c.OK:
+				entry.Info("Selector matches exactly one visible element")
+			default:
+				entry.Warn("Selector does not match exactly one visible element")
+			}
+		}
+		ok = ok && report.OK
+	}
+	return ok
+}
@@ -3,38 +3,74 @@ package cmd
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
+	"io"
 	"os"
 	"time"
 	"xiaoyu/pkg/browser"
+	"xiaoyu/pkg/config"
 	"xiaoyu/pkg/crack"
+	"xiaoyu/pkg/creds"
+	"xiaoyu/pkg/fingerprint"
+	"xiaoyu/pkg/lockout"
+	"xiaoyu/pkg/output"
+	"xiaoyu/pkg/pool"
+	"xiaoyu/pkg/ratelimit"
+	"xiaoyu/pkg/state"
 )
 
 func init() {
 	flags := webLoginCmd.Flags()
 
 	// Cracking flags
-	flags.BoolVar(&globalOptions.crackAll, "crack-all", false, "crack all user and pass")
-	flags.IntVar(&globalOptions.delay, "delay", 1, "delay time between crack")
-	flags.IntVar(&globalOptions.maxAttempts, "max-attempts", 3, "max attempts")
-	flags.IntVar(&globalOptions.maxCrackNum, "max-crack-num", 0, "max crack num, 0 is no limit")
-	flags.IntVar(&globalOptions.maxCrackTime, "max-crack-time", 300, "max crack time in sec")
+	flags.BoolVar(&globalConfig.CrackAll, "crack-all", false, "crack all user and pass")
+	flags.IntVar(&globalConfig.Delay, "delay", config.DefaultDelay, "delay time between crack")
+	flags.IntVar(&globalConfig.MaxAttempts, "max-attempts", config.DefaultMaxAttempts, "max attempts per credential, retried on transient errors")
+	flags.IntVar(&globalConfig.MaxCrackNum, "max-crack-num", 0, "max attempts per target, 0 is no limit")
+	flags.IntVar(&globalConfig.MaxUserCrackNum, "max-user-crack-num", 0, "max attempts per user on a target, 0 is no limit")
+	flags.StringVar(&globalConfig.StopOn, "stop-on", config.DefaultStopOn, "what to stop after a successful login (target|user|never)")
+	flags.IntVar(&globalConfig.MaxSuccess, "max-success", 0, "stop everything after this many successful logins, 0 is no limit")
+	flags.IntVar(&globalConfig.MaxCrackTime, "max-crack-time", config.DefaultMaxCrackTime, "max crack time in sec")
+	flags.BoolVar(&globalConfig.Baseline, "baseline", false, "submit a random invalid credential first and judge attempts by how far they differ from it")
+	flags.StringVar(&globalConfig.LockoutPhrases, "lockout-phrases", "", "extra lockout phrases file (yaml, grouped by language)")
+	flags.StringVar(&globalConfig.LockoutAction, "lockout-action", config.DefaultLockoutAction, "action on account lockout (pause|skip-user|skip-host|abort)")
+	flags.IntVar(&globalConfig.LockoutPause, "lockout-pause", config.DefaultLockoutPause, "pause in seconds when lockout-action is pause")
+	flags.StringVar(&globalConfig.Strategy, "strategy", config.DefaultStrategy, "attempt order: user (all passwords per user) or spray (one password across all users per round)")
+	flags.IntVar(&globalConfig.SprayWait, "spray-wait", config.DefaultSprayWait, "wait in seconds between spray rounds")
+	flags.IntVar(&globalConfig.Rate, "rate", 0, "max login attempts per minute per host, 0 is no limit")
+	flags.IntVar(&globalConfig.Jitter, "jitter", 0, "max random jitter in milliseconds added before each attempt")
+	flags.IntVar(&globalConfig.CooldownAfter, "cooldown-after", 0, "cool an account down after this many consecutive failures, 0 is never")
+	flags.IntVar(&globalConfig.Cooldown, "cooldown", config.DefaultCooldown, "account cooldown in seconds")
+	flags.Float64Var(&globalConfig.BaselineThreshold, "baseline-threshold", config.DefaultBaselineThreshold, "similarity to the baseline at or above which an attempt counts as failed")
 
 	// Detection flags
-	flags.IntVar(&globalOptions.navigationTimeout, "navigation-timeout", 10, "navigation timeout in seconds")
-	flags.IntVar(&globalOptions.elementTimeout, "element-timeout", 5, "element detection timeout in seconds")
-	flags.IntVar(&globalOptions.loginTimeout, "login-all-timeout", 15, "login attempt timeout in seconds")
-
-	flags.StringSliceVar(&globalOptions.userList, "user", nil, "user list, split by comma")
-	flags.StringVar(&globalOptions.userFile, "user-file", "", "user file")
-	flags.StringSliceVar(&globalOptions.passList, "pass", nil, "pass list, split by comma")
-	flags.StringVar(&globalOptions.passFile, "pass-file", "", "pass file")
-	flags.StringVar(&globalOptions.selectorFile, "selector-file", "", "selector file")
-
-	flags.StringVar(&globalOptions.ocrURL, "ocr-url", "http://120.26.57.12:8000", "OCR service URL for captcha solving")
+	flags.IntVar(&globalConfig.NavigationTimeout, "navigation-timeout", config.DefaultNavigationTimeout, "navigation timeout in seconds")
+	flags.IntVar(&globalConfig.ElementTimeout, "element-timeout", config.DefaultElementTimeout, "element detection timeout in seconds")
+	flags.IntVar(&globalConfig.LoginTimeout, "login-all-timeout", config.DefaultLoginTimeout, "login attempt timeout in seconds")
+
+	flags.StringSliceVar(&globalConfig.UserList, "user", nil, "user list, split by comma")
+	flags.StringVar(&globalConfig.UserFile, "user-file", "", "user file, one per line; - reads stdin")
+	flags.StringSliceVar(&globalConfig.PassList, "pass", nil, "pass list, split by comma")
+	flags.StringVar(&globalConfig.PassFile, "pass-file", "", "pass file, one per line; - reads stdin")
+	flags.StringVar(&globalConfig.ComboFile, "combo-file", "", "credential file with user:pass lines, or a .csv with username and password columns; - reads stdin")
+	flags.StringVar(&globalConfig.DefaultCreds, "default-creds", "", "extra default credential file (yaml, per product), used when no password is given")
+	flags.StringVar(&globalConfig.Rules, "rules", "", "password mutation rules file applied to the password list")
+	flags.StringVar(&globalConfig.Company, "company", "", "company name for the %company% placeholder")
+	flags.BoolVar(&globalConfig.DryRun, "dry-run", false, "print the expanded password candidates per user and exit")
+	flags.StringVar(&globalConfig.SelectorFile, "selector-file", "", "selector file")
+	flags.IntVar(&globalConfig.FormIndex, "form-index", config.DefaultFormIndex, "use the form at this position on the page (0-based, see the forms in detection output), -1 picks the highest scoring form; only with a single target, use formIndex in --targets-file otherwise")
+	flags.StringVar(&globalConfig.DetectRules, "detect-rules", "", "form detection dictionary file (yaml, per field and language group) overriding or extending the built-in one")
+	flags.StringVar(&globalConfig.Fingerprints, "fingerprints", "", "extra product fingerprint file or directory of yaml files, overriding built-in products by name")
+
+	flags.StringVar(&globalConfig.OCRURL, "ocr-url", config.DefaultOCRURL, "OCR service URL for captcha solving")
+	flags.BoolVar(&globalConfig.ObserveNetwork, "observe-network", false, "judge logins by the login request's status, JSON body and issued tokens")
+
+	// Resume flags
+	flags.StringVar(&globalConfig.StateFile, "state-file", "", "state file to record finished work for resuming")
+	flags.BoolVar(&globalConfig.Resume, "resume", false, "skip work already recorded in --state-file")
 
 	rootCmd.AddCommand(webLoginCmd)
 }
@@ -48,206 +84,673 @@ var webLoginCmd = &cobra.Command{
   
   # Only detect form elements
   ./weblogin weblogin -i http://example.com:9001 --detect-only`,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		log.Info("Program started")
-
-		log.WithFields(log.Fields{
-			"count": len(globalOptions.inputs),
-		}).Info("Target URLs loaded")
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := resolveConfig(cmd); err != nil {
+			return fmt.Errorf("failed to resolve config: %w", err)
+		}
 
-		printConfiguration(globalOptions)
-	},
-	RunE: func(cmd *cobra.Command, args []string) error {
-		if err := setupLogging(globalOptions.logLevel); err != nil {
+		if err := setupLogging(globalConfig.LogLevel); err != nil {
 			return fmt.Errorf("failed to setup logging: %w", err)
 		}
 
-		cfg, err := loadConfig(globalOptions)
+		log.Info("Program started")
+
+		printConfiguration(globalConfig)
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig(globalConfig)
 		if err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
+		log.WithFields(log.Fields{
+			"count": len(cfg.Targets),
+		}).Info("Target URLs loaded")
+
 		return run(cfg)
 	},
 }
 
-func CreateTasks(flags *Options) []crack.Task {
+func CreateTasks(flags *config.Config, t *config.Target, rules []crack.Rule, defaults *creds.Defaults, product string) []crack.Task {
 	var tasks []crack.Task
-	if flags.crackAll {
-		for _, url := range flags.inputs {
-			for _, user := range flags.userList {
-				for _, pass := range flags.passList {
-					tasks = append(tasks, crack.Task{
-						URL:      url,
-						Username: user,
-						Password: pass,
-					})
-				}
-			}
+	seen := make(map[crack.Task]bool)
+	// 添加一个凭证，按出现顺序去重
+	addPair := func(user, pass string) {
+		task := crack.Task{URL: t.URL, Username: user, Password: pass}
+		if seen[task] {
+			return
+		}
+		seen[task] = true
+		tasks = append(tasks, task)
+	}
+	// 按规则展开该用户的候选密码
+	add := func(user string, passwords []string) {
+		for _, pass := range crack.Mutate(passwords, rules, crack.VarsFor(t.URL, user, t.Company)) {
+			addPair(user, pass)
+		}
+	}
+
+	if flags.CrackAll {
+		for _, user := range t.Users {
+			add(user, t.Passwords)
 		}
 	} else {
-		for _, url := range flags.inputs {
-			for i := range flags.userList {
-				if i < len(flags.passList) {
-					tasks = append(tasks, crack.Task{
-						URL:      url,
-						Username: flags.userList[i],
-						Password: flags.passList[i],
-					})
-				}
+		for i := range t.Users {
+			if i < len(t.Passwords) {
+				add(t.Users[i], t.Passwords[i:i+1])
+			}
+		}
+	}
+
+	// 组合文件中的凭证不受 --crack-all 影响，也不按规则变换，只替换占位符
+	for _, pair := range t.Combos {
+		addPair(pair.Username, crack.Expand(pair.Password, crack.VarsFor(t.URL, pair.Username, t.Company)))
+	}
+
+	// 未指定密码时使用识别出的产品的默认凭证
+	if len(t.Passwords) == 0 && len(t.Combos) == 0 && defaults != nil {
+		// 默认凭证不按规则变换，只替换占位符，引用了未配置的占位符的条目跳过
+		for _, pair := range defaults.Credentials(product, t.Users) {
+			for _, pass := range crack.Mutate([]string{pair.Password}, nil, crack.VarsFor(t.URL, pair.Username, t.Company)) {
+				addPair(pair.Username, pass)
 			}
 		}
 	}
 	return tasks
 }
 
-func GetSelector(ctx context.Context, url string) (s *browser.Selector, err error) {
-	var data []byte
-	var results []map[string]interface{}
-
-	if globalOptions.selectorFile != "" {
-		data, err = os.ReadFile(globalOptions.selectorFile)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read selector file: %w", err)
+// printCandidates
+// @Description: 输出每个目标、每个用户展开后的候选密码（用户名与密码以制表符分隔），用于 --dry-run。
+// 此时尚未识别产品，默认凭证只包含通用部分
+// @param w
+// @param options
+// @param rules
+// @param defaults
+func printCandidates(w io.Writer, options *config.Config, rules []crack.Rule, defaults *creds.Defaults) {
+	for _, t := range options.Targets {
+		tasks := CreateTasks(options, t, rules, defaults, "")
+		fmt.Fprintf(w, "# %s (%d candidates)\n", t.URL, len(tasks))
+		for _, task := range tasks {
+			fmt.Fprintf(w, "%s\t%s\n", task.Username, task.Password)
 		}
+	}
+}
+
+// detectRules 表单探测词典，run 中按 --detect-rules 加载
+var detectRules *browser.DetectRules
 
-		if err = yaml.Unmarshal(data, &s); err != nil {
-			return nil, fmt.Errorf("failed to parse selector file: %w", err)
+// newBrowser
+// @Description: 按目标配置创建浏览器
+// @param t
+// @return *browser.Browser
+// @return error
+func newBrowser(t *config.Target) (*browser.Browser, error) {
+	b, err := browser.New(globalConfig.Headless, t.Proxy, globalConfig.OCRURL)
+	if err != nil {
+		return nil, err
+	}
+	b.SetHeaders(t.Headers)
+	b.SetTimeouts(targetTimeouts(t))
+	b.SetDetectRules(detectRules)
+	if globalConfig.ObserveNetwork {
+		b.ObserveNetwork()
+	}
+	return b, nil
+}
+
+// targetTimeouts
+// @Description: 目标的各阶段超时
+// @param t
+// @return browser.Timeouts
+func targetTimeouts(t *config.Target) browser.Timeouts {
+	return browser.Timeouts{
+		Navigation: time.Duration(t.NavigationTimeout) * time.Second,
+		Element:    time.Duration(t.ElementTimeout) * time.Second,
+		Login:      time.Duration(t.LoginTimeout) * time.Second,
+	}
+}
+
+// Detection
+// @Description: 自动探测选择器时的附加信息，写入探测结果
+type Detection struct {
+	Match *fingerprint.Match  // 识别出的产品
+	Forms []*browser.FormDesc // 按分数排列的候选表单
+}
+
+func GetSelector(ctx context.Context, t *config.Target, db *fingerprint.DB) (s *browser.Selector, d *Detection, err error) {
+	url := t.URL
+	d = &Detection{}
+
+	if t.Selector != nil {
+		// 配置中内联的选择器，或 loadConfig 中已加载的选择器文件
+		s = t.Selector
+	} else if t.SelectorFile != "" {
+		if s, err = loadSelectorFile(t.SelectorFile); err != nil {
+			return nil, d, err
 		}
 	} else {
 		var b *browser.Browser
-		b, err = browser.New(globalOptions.headless, globalOptions.proxy, globalOptions.ocrURL)
+		b, err = newBrowser(t)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create browser: %w", err)
+			return nil, d, fmt.Errorf("failed to create browser: %w", err)
 		}
 
 		// 释放资源
 		defer b.Close()
 
 		// 创建带有超时的上下文
-		navigateCtx, cancel := context.WithTimeout(ctx, time.Duration(globalOptions.navigationTimeout)*time.Second)
+		navigateCtx, cancel := context.WithTimeout(ctx, time.Duration(t.NavigationTimeout)*time.Second)
 		defer cancel()
 
 		// 访问网站
 		if err = b.Navigate(navigateCtx, url); err != nil {
 			log.WithError(err).Errorf("Failed to navigate to URL: %s", url)
-			return nil, err
+			return nil, d, err
 		}
 
-		// 探测选择器
-		s, err = b.DetectFormSelectors()
+		// 先按产品指纹匹配，命中时使用该产品的选择器
+		s, d.Match, err = db.Identify(ctx, b)
 		if err != nil {
-			log.WithError(err).Errorf("Failed to detect_form_and_selectors for URL: %s", url)
-			return nil, err
+			log.WithError(err).Warnf("Failed to fingerprint URL: %s", url)
+		}
+		if s != nil && s.LoginBtn != "" {
+			return s, d, nil
 		}
-	}
 
-	// 打印探测信息
-	result := map[string]interface{}{
-		"url": url,
-		"selectors": map[string]string{
-			"userInput":     s.UserInput,
-			"passwordInput": s.PasswordInput,
-			"loginBtn":      s.LoginBtn,
-			"rememberMe":    s.RememberMe,
-		},
-	}
+		// 为全部表单评分后按目标的 formIndex 或分数选择，产品未配置登录按钮时只用探测结果补全按钮
+		var form *browser.FormDesc
+		if d.Forms, err = b.DetectForms(ctx); err == nil {
+			form, err = browser.SelectForm(d.Forms, formIndex(t))
+		}
+		if err == nil {
+			browser.LogForm(form)
+		}
 
-	// 保存结果
-	results = append(results, result)
-	err = saveResults(results, globalOptions.outputFile)
-	if err != nil {
-		log.WithError(err).Errorf("Failed to save selector result for URL: %s", url)
-		return nil, err
+		if s != nil {
+			// 已确认产品的选择器不因表单探测失败而丢弃，改为在整个页面中按词典查找登录按钮
+			var buttons []string
+			if err == nil {
+				buttons = form.Selector().Candidates(browser.FieldLoginBtn)
+			} else {
+				log.WithError(err).Warnf("Failed to detect login form for URL: %s, searching the whole page for the login button", url)
+			}
+			if len(buttons) == 0 {
+				buttons = b.PageCandidates(browser.FieldLoginBtn)
+			}
+			s.SetCandidates(browser.FieldLoginBtn, buttons)
+			return s, d, nil
+		}
+		if err != nil {
+			log.WithError(err).Errorf("Failed to detect_form_and_selectors for URL: %s", url)
+			return nil, d, err
+		}
+		s = form.Selector()
 	}
 
-	return s, nil
+	return s, d, nil
 }
 
-func Crack(ctx context.Context, task crack.Task, s *browser.Selector) {
-	var b *browser.Browser
-	var err error
+// formIndex
+// @Description: 目标使用的表单序号，未配置时按分数选择
+// @param t
+// @return int
+func formIndex(t *config.Target) int {
+	if t.FormIndex == nil {
+		return config.DefaultFormIndex
+	}
+	return *t.FormIndex
+}
 
-	b, err = browser.New(globalOptions.headless, globalOptions.proxy, globalOptions.ocrURL)
+// RecordBaseline
+// @Description: 用随机无效凭证登录一次，记录目标的失败基线
+// @param ctx
+// @param t
+// @param s
+// @return *browser.Snapshot
+// @return error
+func RecordBaseline(ctx context.Context, t *config.Target, s *browser.Selector) (*browser.Snapshot, error) {
+	b, err := newBrowser(t)
 	if err != nil {
-		return
+		return nil, fmt.Errorf("failed to create browser: %w", err)
 	}
-
-	// 释放资源
 	defer b.Close()
 
-	// 超时上下文
-	navigateCtx, cancel := context.WithTimeout(ctx, time.Duration(globalOptions.navigationTimeout)*time.Second)
+	navigateCtx, cancel := context.WithTimeout(ctx, time.Duration(t.NavigationTimeout)*time.Second)
 	defer cancel()
 
-	// 访问网站
-	if err = b.Navigate(navigateCtx, task.URL); err != nil {
-		log.WithError(err).Errorf("Failed to navigate to URL: %s", task.URL)
-		return
+	if err = b.Navigate(navigateCtx, t.URL); err != nil {
+		return nil, err
 	}
 
+	cracker := crack.New(globalConfig.Delay, globalConfig.MaxAttempts, b, s)
+	cracker.SetTimeouts(targetTimeouts(t))
+	return cracker.RecordBaseline(ctx)
+}
+
+// session
+// @Description: 一次运行中所有任务共享的状态
+type session struct {
+	guard   *lockout.Guard
+	limiter *ratelimit.Limiter
+	stopper *crack.Stopper
+	sink    *output.Writer
+	store   *state.Store
+
+	fingerprints *fingerprint.DB
+}
+
+func Crack(ctx context.Context, t *config.Target, task crack.Task, s *browser.Selector, baseline *browser.Snapshot, sess *session) []crack.Result {
+	// 浏览器在通过锁定暂停和频率限制后才由 cracker 启动
+	var b *browser.Browser
+	defer func() {
+		// 释放资源
+		if b != nil {
+			b.Close()
+		}
+	}()
+
 	// 初始化对象
 	cracker := crack.New(
-		globalOptions.delay,
-		globalOptions.maxAttempts,
-		globalOptions.maxCrackNum,
-		globalOptions.maxCrackTime,
-		globalOptions.threads,
-		b,
+		globalConfig.Delay,
+		globalConfig.MaxAttempts,
+		nil,
 		s,
 	)
+	cracker.SetLauncher(func() (*browser.Browser, error) {
+		var err error
+		b, err = newBrowser(t)
+		return b, err
+	})
+	cracker.SetTimeouts(targetTimeouts(t))
+	cracker.SetBaseline(baseline, globalConfig.BaselineThreshold)
+	cracker.SetLockout(sess.guard)
+	cracker.SetRateLimit(sess.limiter)
+	cracker.SetStopper(sess.stopper)
+	cracker.SetCheckpoint(checkpoint{store: sess.store})
+	cracker.OnResult(func(result crack.Result) {
+		if err := sess.sink.Write(output.TypeAttempt, result.Task.URL, attemptRecord(result)); err != nil {
+			log.WithError(err).Error("Failed to write attempt record")
+		}
+		if result.Success {
+			if err := sess.sink.Write(output.TypeSuccess, result.Task.URL, attemptRecord(result)); err != nil {
+				log.WithError(err).Error("Failed to write success record")
+			}
+		}
+	})
 
-	crackCtx, crackCancel := context.WithTimeout(ctx, time.Duration(globalOptions.maxCrackTime)*time.Second)
+	crackCtx, crackCancel := context.WithTimeout(ctx, time.Duration(globalConfig.MaxCrackTime)*time.Second)
 	defer crackCancel()
 
 	// 登录网站
-	results := cracker.SingleTaskCrack(crackCtx, task)
+	return cracker.SingleTaskCrack(crackCtx, task)
+}
+
+// logPlan
+// @Description: 输出目标的执行计划与估计耗时
+// @param options
+// @param t
+// @param strategy
+// @param rounds
+func logPlan(options *config.Config, t *config.Target, strategy crack.Strategy, rounds [][]crack.Task) {
+	attempts := 0
+	for _, round := range rounds {
+		attempts += len(round)
+	}
+	estimate := crack.Estimate(rounds, crack.Pacing{
+		Concurrency: options.HostThreads,
+		Delay:       time.Duration(options.Delay) * time.Second,
+		Rate:        options.Rate,
+		Jitter:      time.Duration(options.Jitter) * time.Millisecond,
+		SprayWait:   time.Duration(options.SprayWait) * time.Second,
+	})
+	log.WithFields(log.Fields{
+		"url":       t.URL,
+		"strategy":  strategy,
+		"rounds":    len(rounds),
+		"attempts":  attempts,
+		"estimated": estimate.Round(time.Second).String(),
+	}).Info("Attack plan")
+}
 
-	// 保存记录
-	if len(results) > 0 {
-		saveResults(results, globalOptions.outputFile)
+// attemptRecord
+// @Description: 将登录结果转换为可序列化的记录
+// @param result
+// @return map[string]interface{}
+func attemptRecord(result crack.Result) map[string]interface{} {
+	record := map[string]interface{}{
+		"username": result.Task.Username,
+		"password": result.Task.Password,
+		"success":  result.Success,
+		"code":     result.Code,
+		"message":  result.Message,
+		"attempts": result.Attempts,
+		"limits":   result.Limits,
+		"matched":  result.Matched,
+		"rule":     result.Rule,
+	}
+	if len(result.Tokens) > 0 {
+		record["tokens"] = result.Tokens
 	}
+	if globalConfig.Baseline {
+		record["similarity"] = result.Similarity
+	}
+	return record
+}
 
+// checkpoint
+// @Description: 将 state.Store 适配为 crack.Checkpoint
+type checkpoint struct {
+	store *state.Store
 }
 
-func run(options *Options) error {
-	var err error
+func (c checkpoint) IsDone(task crack.Task) bool {
+	// 该用户已成功登录的话，剩余密码无需再试
+	return c.store.Done(state.KindSuccess, task.URL, task.Username) ||
+		c.store.Done(state.KindAttempt, task.URL, task.Username, task.Password)
+}
 
-	for _, url := range options.inputs {
-		var s *browser.Selector
+func (c checkpoint) MarkDone(result crack.Result) error {
+	task := result.Task
+	if err := c.store.Mark(state.KindAttempt, nil, task.URL, task.Username, task.Password); err != nil {
+		return err
+	}
+	if result.Success {
+		return c.store.Mark(state.KindSuccess, nil, task.URL, task.Username)
+	}
+	return nil
+}
 
-		// 获取选择器
-		s, err = GetSelector(gCtx, url)
-		if err != nil {
-			log.Fatal("try to get selector error.")
-		}
+func run(options *config.Config) (err error) {
+	ctx, cancel := context.WithCancelCause(gCtx)
+	defer cancel(nil)
+	p := pool.New(options.Threads, options.HostThreads)
 
-		if s == nil {
-			log.Fatal("get selector is nil.")
+	// 密码变换规则
+	var rules []crack.Rule
+	if options.Rules != "" {
+		if rules, err = crack.LoadRules(options.Rules); err != nil {
+			return err
 		}
+		log.WithFields(log.Fields{
+			"file":  options.Rules,
+			"count": len(rules),
+		}).Info("Password rules loaded")
+	}
+	// 默认凭证，未指定密码时按产品使用
+	defaults, err := creds.LoadDefaults(options.DefaultCreds)
+	if err != nil {
+		return err
+	}
+	if options.DryRun {
+		printCandidates(os.Stdout, options, rules, defaults)
+		return nil
+	}
 
-		// 输出匹配信息
-		selectorJSON, _ := json.Marshal(map[string]interface{}{
-			"userInput":     s.UserInput,
-			"passwordInput": s.PasswordInput,
-			"loginBtn":      s.LoginBtn,
-			"rememberMe":    s.RememberMe,
-			"captchaInput":  s.CaptchaInput,
-			"captchaImg":    s.CaptchaImg,
-		})
+	strategy, err := crack.ParseStrategy(options.Strategy)
+	if err != nil {
+		return err
+	}
+	stopOn, err := crack.ParseStopOn(options.StopOn)
+	if err != nil {
+		return err
+	}
 
-		log.WithField("selector", string(selectorJSON)).Debug("found selectors successfully")
+	// 锁定检测，abort 时取消整个任务
+	action, err := lockout.ParseAction(options.LockoutAction)
+	if err != nil {
+		return err
+	}
+	catalog, err := lockout.LoadCatalog(options.LockoutPhrases)
+	if err != nil {
+		return err
+	}
+	guard := lockout.NewGuard(lockout.NewDetector(catalog), action, time.Duration(options.LockoutPause)*time.Second, func(reason string) {
+		cancel(fmt.Errorf("%w: %s", errLockoutAbort, reason))
+	})
+
+	// 表单探测词典
+	if detectRules, err = browser.LoadDetectRules(options.DetectRules); err != nil {
+		return err
+	}
 
-		// 仅探测
-		if options.detectOnly {
-			continue
+	// 产品指纹：内置的 framework 列表，再叠加 --fingerprints
+	fingerprints, err := loadFingerprints(options.Fingerprints)
+	if err != nil {
+		return err
+	}
+
+	sink, err := output.New(options.OutputFile, options.StreamFile, options.Resume)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// 合并输出结果
+		if closeErr := sink.Close(); closeErr != nil && err == nil {
+			err = closeErr
 		}
+	}()
 
-		for _, task := range CreateTasks(options) {
-			Crack(gCtx, task, s)
+	// 断点续跑状态
+	var store *state.Store
+	if options.StateFile != "" {
+		if store, err = state.Open(options.StateFile, options.Resume); err != nil {
+			return err
 		}
+		defer store.Close()
 
+		log.WithFields(log.Fields{
+			"file":     options.StateFile,
+			"resume":   options.Resume,
+			"finished": store.Count(),
+		}).Info("State file loaded")
+	} else if options.Resume {
+		return fmt.Errorf("--resume requires --state-file")
 	}
 
+	sess := &session{
+		guard: guard,
+		limiter: ratelimit.New(
+			options.Rate,
+			time.Duration(options.Jitter)*time.Millisecond,
+			options.CooldownAfter,
+			time.Duration(options.Cooldown)*time.Second,
+		),
+		stopper: crack.NewStopper(crack.StopPolicy{
+			MaxPerTarget: options.MaxCrackNum,
+			MaxPerUser:   options.MaxUserCrackNum,
+			On:           stopOn,
+			MaxSuccesses: options.MaxSuccess,
+		}, func(stop crack.Stop) {
+			log.WithFields(log.Fields{
+				"url":      stop.URL,
+				"scope":    stop.Scope,
+				"username": stop.Username,
+				"reason":   stop.Reason,
+				"attempts": stop.Attempts,
+			}).Info("Stop condition reached")
+			if err := sink.Write(output.TypeStop, stop.URL, stop); err != nil {
+				log.WithError(err).Error("Failed to write stop record")
+			}
+		}),
+		sink:  sink,
+		store: store,
+
+		fingerprints: fingerprints,
+	}
+
+	for _, t := range options.Targets {
+		t := t
+		url := t.URL
+
+		p.Submit(ctx, pool.HostOf(url), func(ctx context.Context) {
+			// 获取选择器，续跑时复用上次的探测结果
+			s := &browser.Selector{}
+			d := &Detection{}
+			resumed := store.Load(state.KindDetection, s, url)
+			if resumed {
+				// 续跑时沿用上次识别出的产品，保证默认凭证与上次一致
+				d.Match = &fingerprint.Match{}
+				if !store.Load(state.KindProduct, d.Match, url) {
+					d.Match = nil
+				}
+			}
+			if !resumed {
+				var err error
+				s, d, err = GetSelector(ctx, t, sess.fingerprints)
+				if err != nil {
+					log.WithError(err).Errorf("Failed to get selector for URL: %s", url)
+					if ctx.Err() == nil {
+						classified := crack.Classify(err, "")
+						detection := map[string]interface{}{
+							"code":    classified.Code,
+							"message": classified.Error(),
+							"notes":   t.Notes,
+						}
+						if len(d.Forms) > 0 {
+							detection["forms"] = d.Forms
+						}
+						if err := sink.Write(output.TypeDetection, url, detection); err != nil {
+							log.WithError(err).Errorf("Failed to save detection result for URL: %s", url)
+						}
+					}
+					return
+				}
+
+				if s == nil {
+					log.Errorf("Selector is nil for URL: %s", url)
+					return
+				}
+			}
+
+			// 输出匹配信息（有候选列表的字段输出为列表）
+			if !resumed {
+				detection := map[string]interface{}{
+					"selectors": s,
+					"notes":     t.Notes,
+				}
+				if d.Match != nil {
+					detection["product"] = d.Match.Name
+					detection["evidence"] = d.Match.Evidence
+				}
+				if len(d.Forms) > 0 {
+					detection["forms"] = d.Forms
+				}
+				if err := sink.Write(output.TypeDetection, url, detection); err != nil {
+					log.WithError(err).Errorf("Failed to save selector result for URL: %s", url)
+				}
+				if err := store.Mark(state.KindDetection, s, url); err != nil {
+					log.WithError(err).Error("Failed to save checkpoint")
+				}
+				if d.Match != nil {
+					if err := store.Mark(state.KindProduct, d.Match, url); err != nil {
+						log.WithError(err).Error("Failed to save checkpoint")
+					}
+				}
+			}
+
+			selectorJSON, _ := json.Marshal(s)
+			log.WithField("selector", string(selectorJSON)).Debug("found selectors successfully")
+
+			// 仅探测
+			if options.DetectOnly {
+				return
+			}
+
+			// 失败基线，录制失败时退回规则判定
+			var baseline *browser.Snapshot
+			if options.Baseline {
+				var err error
+				if baseline, err = RecordBaseline(ctx, t, s); err != nil {
+					log.WithError(err).Warnf("Failed to record baseline for URL: %s", url)
+				} else {
+					log.WithFields(log.Fields{
+						"url":     url,
+						"final":   baseline.URL,
+						"domHash": baseline.DOMHash,
+					}).Info("Failure baseline recorded")
+				}
+			}
+
+			product := ""
+			if d.Match != nil {
+				product = d.Match.Name
+			}
+			rounds := crack.Plan(CreateTasks(options, t, rules, defaults, product), strategy)
+			logPlan(options, t, strategy, rounds)
+
+			// 提交一轮任务
+			submit := func(ctx context.Context, g *pool.Group, round []crack.Task) {
+				for _, task := range round {
+					task := task
+					// 已完成的任务在启动浏览器前跳过
+					if (checkpoint{store: store}).IsDone(task) {
+						continue
+					}
+					g.Submit(ctx, pool.HostOf(task.URL), func(ctx context.Context) {
+						// 排队期间该用户或主机可能已因锁定被放弃，或已满足停止条件
+						if reason, blocked := guard.Blocked(pool.HostOf(task.URL), task.Username); blocked {
+							log.WithFields(log.Fields{
+								"url":      task.URL,
+								"username": task.Username,
+								"reason":   reason,
+							}).Debug("Skipping task after account lockout")
+							return
+						}
+						if reason, stopped := sess.stopper.Stopped(task); stopped {
+							log.WithFields(log.Fields{
+								"url":      task.URL,
+								"username": task.Username,
+								"reason":   reason,
+							}).Debug("Skipping task after stop condition")
+							return
+						}
+						Crack(ctx, t, task, s, baseline, sess)
+					})
+				}
+			}
+
+			// 调度协程不占用槽位，逐轮提交并等待；密码喷洒时两轮之间等待
+			p.Go(ctx, func(ctx context.Context) {
+				defer func() {
+					sess.stopper.Finish(url, ctx.Err() != nil)
+				}()
+				for i, round := range rounds {
+					if i > 0 && strategy == crack.StrategySpray {
+						// 目标已停止时无需再等待
+						if _, stopped := sess.stopper.Stopped(crack.Task{URL: url}); stopped {
+							return
+						}
+						log.WithFields(log.Fields{
+							"url":   url,
+							"round": i + 1,
+							"wait":  time.Duration(options.SprayWait) * time.Second,
+						}).Info("Waiting before next spray round")
+						select {
+						case <-ctx.Done():
+							return
+						case <-time.After(time.Duration(options.SprayWait) * time.Second):
+						}
+					}
+					g := p.Group()
+					submit(ctx, g, round)
+					g.Wait()
+				}
+			})
+		})
+	}
+
+	// 等待所有worker结束（或上下文取消）
+	p.Wait()
+
+	if cause := context.Cause(ctx); errors.Is(cause, errLockoutAbort) {
+		return fmt.Errorf("run aborted, partial results saved: %w", cause)
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("run interrupted, partial results saved: %w", ctx.Err())
+	}
 	return nil
 }
+
+// errLockoutAbort 检测到锁定且处理方式为 abort
+var errLockoutAbort = errors.New("account lockout detected")
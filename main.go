@@ -1,11 +1,17 @@
 package main
 
 import (
+	_ "embed"
 	log "github.com/sirupsen/logrus"
 	"os"
 	"xiaoyu/cmd"
 )
 
+// frameworkConfig 内置的产品指纹与登录页选择器
+//
+//go:embed config.yaml
+var frameworkConfig []byte
+
 func init() {
 	log.SetFormatter(&log.TextFormatter{
 		TimestampFormat: "2006-01-02 15:04:05",
@@ -18,5 +24,6 @@ func init() {
 }
 
 func main() {
+	cmd.SetFrameworks(frameworkConfig)
 	cmd.Execute()
 }
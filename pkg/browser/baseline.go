@@ -0,0 +1,273 @@
+package browser
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// 各部分在相似度中的权重
+const (
+	weightURL       = 0.2
+	weightDOM       = 0.3
+	weightText      = 0.3
+	weightCookies   = 0.1
+	weightResponses = 0.1
+)
+
+// Snapshot
+// @Description: 提交登录后页面状态的快照，用于和失败基线比较
+type Snapshot struct {
+	URL       string         `json:"url"`
+	DOMHash   string         `json:"domHash"`
+	Structure map[string]int `json:"-"`
+	Text      map[string]int `json:"-"`
+	Cookies   []string       `json:"cookies"`
+	Responses []string       `json:"responses,omitempty"`
+}
+
+// snapshotJS 页面结构（深度:标签）与可见文本
+const snapshotJS = `() => {
+	const structure = [];
+	const walk = (el, depth) => {
+		if (depth > 16) return;
+		for (const child of el.children) {
+			const type = child.tagName === 'INPUT' ? '[' + (child.type || 'text') + ']' : '';
+			structure.push(depth + ':' + child.tagName + type);
+			walk(child, depth + 1);
+		}
+	};
+	if (document.body) walk(document.body, 0);
+	return { structure, text: document.body ? document.body.innerText : '' };
+}`
+
+// Snapshot
+// @Description: 记录当前页面的 URL、DOM 结构、可见文本、Cookie 以及观察到的网络响应
+// @receiver b
+// @param ctx
+// @return *Snapshot
+// @return error
+func (b *Browser) Snapshot(ctx context.Context) (*Snapshot, error) {
+	page := b.query(ctx)
+
+	res, err := page.Eval(snapshotJS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot page: %w", err)
+	}
+
+	s := &Snapshot{
+		URL:       b.currentURL(),
+		Structure: make(map[string]int),
+	}
+
+	var paths []string
+	for _, item := range res.Value.Get("structure").Arr() {
+		paths = append(paths, item.Str())
+		s.Structure[item.Str()]++
+	}
+	sum := sha1.Sum([]byte(strings.Join(paths, "\n")))
+	s.DOMHash = hex.EncodeToString(sum[:])
+
+	s.Text = TextShingles(res.Value.Get("text").Str())
+
+	if cookies, err := page.Cookies(nil); err == nil {
+		for _, cookie := range cookies {
+			s.Cookies = append(s.Cookies, cookie.Name)
+		}
+		sort.Strings(s.Cookies)
+	}
+
+	s.Responses = b.observedResponses()
+	return s, nil
+}
+
+// maxSnapshotText 参与比较的文本最大字符数
+const maxSnapshotText = 20000
+
+// TextShingles
+// @Description: 文本的相邻字符二元组计数，对中文和少量动态内容（剩余次数、时间）都比较稳定
+// @param text
+// @return map[string]int
+func TextShingles(text string) map[string]int {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	if len(runes) > maxSnapshotText {
+		runes = runes[:maxSnapshotText]
+	}
+
+	shingles := make(map[string]int)
+	for i := 0; i+1 < len(runes); i++ {
+		shingles[string(runes[i:i+2])]++
+	}
+	return shingles
+}
+
+// observedResponses
+// @Description: 本次登录观察到的非 GET 请求的响应，格式为 "METHOD 状态码 路径"
+// @receiver b
+// @return []string
+func (b *Browser) observedResponses() []string {
+	b.mu.Lock()
+	o := b.observer
+	b.mu.Unlock()
+	if o == nil {
+		return nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var list []string
+	for _, ex := range o.exchanges {
+		if ex.Method == "GET" {
+			continue
+		}
+		path := ex.URL
+		if u, err := url.Parse(ex.URL); err == nil {
+			path = u.Path
+		}
+		list = append(list, fmt.Sprintf("%s %d %s", ex.Method, ex.Status, path))
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Similarity
+// @Description: 两个快照的相似度，1 表示完全相同
+// @param a
+// @param b
+// @return float64
+func Similarity(a, b *Snapshot) float64 {
+	urlScore := 0.0
+	if a.URL == b.URL {
+		urlScore = 1
+	}
+
+	domScore := 1.0
+	if a.DOMHash != b.DOMHash {
+		domScore = overlap(a.Structure, b.Structure)
+	}
+
+	return weightURL*urlScore +
+		weightDOM*domScore +
+		weightText*overlap(a.Text, b.Text) +
+		weightCookies*overlap(countOf(a.Cookies), countOf(b.Cookies)) +
+		weightResponses*overlap(countOf(a.Responses), countOf(b.Responses))
+}
+
+func countOf(list []string) map[string]int {
+	m := make(map[string]int, len(list))
+	for _, item := range list {
+		m[item]++
+	}
+	return m
+}
+
+// overlap
+// @Description: 多重集合的 Jaccard 相似度，两边都为空时视为相同
+// @param a
+// @param b
+// @return float64
+func overlap(a, b map[string]int) float64 {
+	inter, union := 0, 0
+	for key, x := range a {
+		y := b[key]
+		inter += min(x, y)
+		union += max(x, y)
+	}
+	for key, y := range b {
+		if _, ok := a[key]; !ok {
+			union += y
+		}
+	}
+	if union == 0 {
+		return 1
+	}
+	return float64(inter) / float64(union)
+}
+
+// SetBaseline
+// @Description: 设置失败基线。提交后等待 settle，与基线的相似度不低于 threshold 视为失败，否则视为成功；
+// 选择器中配置的规则或自定义判定在等待期间仍然优先
+// @receiver b
+// @param snapshot
+// @param threshold
+func (b *Browser) SetBaseline(snapshot *Snapshot, threshold float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.baseline = snapshot
+	b.baselineThreshold = threshold
+}
+
+// SubmitSnapshot
+// @Description: 提交一组凭证，等待页面稳定后记录快照，用于录制失败基线
+// @receiver b
+// @param ctx
+// @param selector
+// @param username
+// @param password
+// @return *Snapshot
+// @return error
+func (b *Browser) SubmitSnapshot(ctx context.Context, selector *Selector, username, password string) (*Snapshot, error) {
+	b.mu.Lock()
+	b.username, b.password = username, password
+	b.mu.Unlock()
+	b.startCapture(ctx)
+
+	if err := b.performLogin(ctx, selector, username, password); err != nil {
+		return nil, err
+	}
+	if err := sleep(ctx, BaselineSettle); err != nil {
+		return nil, err
+	}
+	return b.Snapshot(ctx)
+}
+
+// baselineOracle
+// @Description: 基于失败基线的判定，替代默认判定中容易误判的“已离开登录页”规则
+type baselineOracle struct {
+	inner     Oracle // 选择器中配置的规则或自定义判定，可为空
+	baseline  *Snapshot
+	threshold float64
+}
+
+func (o baselineOracle) Evaluate(ctx context.Context, b *Browser) Verdict {
+	var verdict Verdict
+	if o.inner != nil {
+		verdict = o.inner.Evaluate(ctx, b)
+	}
+
+	b.mu.Lock()
+	submittedAt := b.submittedAt
+	b.mu.Unlock()
+
+	// 提交前或等待页面稳定期间只看规则
+	decisive := verdict.Success || verdict.Failed
+	if submittedAt.IsZero() || (!decisive && time.Since(submittedAt) < BaselineSettle) {
+		return verdict
+	}
+
+	snapshot, err := b.Snapshot(ctx)
+	if err != nil {
+		log.WithError(err).Debug("Failed to snapshot page for baseline comparison")
+		return verdict
+	}
+	score := Similarity(o.baseline, snapshot)
+	verdict.Similarity = score
+	if decisive {
+		return verdict
+	}
+
+	rule := fmt.Sprintf("baseline:similarity=%.2f", score)
+	if score >= o.threshold {
+		return Verdict{Failed: true, Rule: rule, Similarity: score}
+	}
+	return Verdict{Success: true, Rule: rule, Similarity: score}
+}
@@ -15,15 +15,50 @@ import (
 )
 
 type Browser struct {
-	browser *rod.Browser
-	page    *rod.Page
-	mu      sync.Mutex
+	browser  *rod.Browser
+	launcher *launcher.Launcher
+	page     *rod.Page
+	mu       sync.Mutex
 
 	captchaHandler *CaptchaHandler      // Handler for processing captcha challenges
 	authTokens     map[string]string    // Store detected auth tokens
 	lastStatus     int                  // Store last HTTP status code
+	lastHeaders    proto.NetworkHeaders // Response headers of the main document
 	lastResponse   string               // Store last response body for error detection
 	selectorCache  map[string]*Selector // Cache successful selectors by URL for better performance
+	headers        map[string]string    // Extra HTTP headers sent with every request
+	timeouts       Timeouts             // Per-phase timeouts
+	matched        map[string]string    // Selector candidate matched per field in the last login
+	oracle         Oracle               // Custom login verdict, defaults to the selector's indicators
+	verdict        Verdict              // Verdict of the last login
+	stopEvents     context.CancelFunc   // Stops the event listener of the current page
+	observer       *networkObserver     // Records login requests when network observation is on
+	username       string               // Username of the current login
+	password       string               // Password of the current login
+	detectRules    *DetectRules         // Keyword dictionaries for form detection
+
+	baseline          *Snapshot // Failure baseline recorded with an invalid credential
+	baselineThreshold float64   // Similarity at or above which an attempt counts as failed
+	submittedAt       time.Time // When the current login form was submitted
+}
+
+// Timeouts
+// @Description: 各阶段超时：页面导航、单个元素等待、提交后等待登录结果
+type Timeouts struct {
+	Navigation time.Duration
+	Element    time.Duration
+	Login      time.Duration
+}
+
+// DefaultTimeouts
+// @Description: 默认超时
+// @return Timeouts
+func DefaultTimeouts() Timeouts {
+	return Timeouts{
+		Navigation: DefaultNavigationTimeout,
+		Element:    DefaultElementTimeout,
+		Login:      DefaultLoginTimeout,
+	}
 }
 
 var MyDevice = devices.Device{
@@ -72,23 +107,27 @@ func New(headless bool, proxy string, ocrBaseURL string) (*Browser, error) {
 		l = l.Proxy(proxy)
 	}
 
-	browser := rod.New().ControlURL(l.MustLaunch()).Timeout(30 * time.Second).MustConnect()
+	// 连接本身不能带超时，否则超时后整个会话都会失效
+	controlURL, err := l.Launch()
+	if err != nil {
+		return nil, fmt.Errorf("failed to launch browser: %w", err)
+	}
+
+	browser := rod.New().ControlURL(controlURL)
+	if err = browser.Connect(); err != nil {
+		l.Kill()
+		return nil, fmt.Errorf("failed to connect browser: %w", err)
+	}
 	//browser.DefaultDevice(MyDevice)
 
 	b := &Browser{
 		browser:       browser,
+		launcher:      l,
 		authTokens:    make(map[string]string),
 		selectorCache: make(map[string]*Selector),
+		timeouts:      DefaultTimeouts(),
 	}
 
-	// 网络流量监听器
-	// router := browser.HijackRequests()
-	// router.MustAdd("*", func(ctx *rod.Hijack) {
-	//	b.handleNetworkResponse(ctx)
-	//	ctx.ContinueRequest(&proto.FetchContinueRequest{})
-	// })
-	//go router.Run()
-
 	// 初始化OCR识别处理器
 	if ocrBaseURL != "" {
 		captchaHandler, err := NewCaptchaHandler(b, ocrBaseURL)
@@ -106,8 +145,23 @@ func New(headless bool, proxy string, ocrBaseURL string) (*Browser, error) {
 // @receiver b
 // @return error
 func (b *Browser) Close() error {
-	if b.page != nil {
-		if err := b.page.Close(); err != nil {
+	// 无论是否出错都要保证 Chrome 进程退出
+	defer func() {
+		if b.launcher != nil {
+			b.launcher.Kill()
+			b.launcher.Cleanup()
+		}
+	}()
+
+	b.mu.Lock()
+	if b.stopEvents != nil {
+		b.stopEvents()
+	}
+	b.mu.Unlock()
+
+	if page := b.GetPage(); page != nil {
+		if err := page.Close(); err != nil {
+			_ = b.browser.Close()
 			return fmt.Errorf("failed to close page: %w", err)
 		}
 	}
@@ -117,53 +171,99 @@ func (b *Browser) Close() error {
 	return nil
 }
 
-func (b *Browser) IsLoggedIn() bool {
-	// Check for common login success indicators
-	successIndicators := []string{
-		".user-info",
-		".user-profile",
-		".logout-btn",
-		"#logout",
-		".welcome-message",
-	}
+// sleep
+// @Description: 可被上下文取消的等待
+// @param ctx
+// @param d
+// @return error
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
 
-	for _, selector := range successIndicators {
-		if el, err := b.page.Element(selector); err == nil && el != nil {
-			if visible, _ := el.Visible(); visible {
-				return true
-			}
-		}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
 	}
+}
 
-	// Check URL for login-related paths
-	currentURL := b.page.MustInfo().URL
-	loginPaths := []string{"/login", "/signin", "/auth"}
-	for _, path := range loginPaths {
-		if strings.Contains(currentURL, path) {
-			return false
-		}
-	}
+// query
+// @Description: 返回绑定调用方上下文的页面对象，查不到元素时立即返回，由调用方控制重试
+// @receiver b
+// @param ctx
+// @return *rod.Page
+func (b *Browser) query(ctx context.Context) *rod.Page {
+	return b.GetPage().Context(ctx).Sleeper(rod.NotFoundSleeper)
+}
 
-	// Check for error messages
-	errorIndicators := []string{
-		".error-message",
-		".alert-error",
-		".login-error",
-		".colorR",
+// currentURL
+// @Description: 当前页面URL，获取失败时返回空字符串
+// @receiver b
+// @return string
+func (b *Browser) currentURL() string {
+	page := b.GetPage()
+	if page == nil {
+		return ""
+	}
+	info, err := page.Info()
+	if err != nil {
+		return ""
 	}
+	return info.URL
+}
 
-	for _, selector := range errorIndicators {
-		if el, err := b.page.Element(selector); err == nil && el != nil {
-			if visible, _ := el.Visible(); visible {
-				return false
-			}
-		}
+// SetHeaders
+// @Description: 设置每个请求附带的额外请求头，在下一次 Navigate 时生效
+// @receiver b
+// @param headers
+func (b *Browser) SetHeaders(headers map[string]string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.headers = headers
+}
+
+// SetTimeouts
+// @Description: 设置各阶段超时，未设置（<=0）的阶段保持默认值
+// @receiver b
+// @param t
+func (b *Browser) SetTimeouts(t Timeouts) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if t.Navigation > 0 {
+		b.timeouts.Navigation = t.Navigation
+	}
+	if t.Element > 0 {
+		b.timeouts.Element = t.Element
 	}
+	if t.Login > 0 {
+		b.timeouts.Login = t.Login
+	}
+}
 
-	return true
+// GetTimeouts
+// @Description: 获取当前生效的各阶段超时
+// @receiver b
+// @return Timeouts
+func (b *Browser) GetTimeouts() Timeouts {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.timeouts
 }
 
-func (b *Browser) findElement(selector, name string) (*rod.Element, error) {
+// IsLoggedIn
+// @Description: 按当前判定检查是否已登录成功
+// @receiver b
+// @param ctx
+// @return bool
+func (b *Browser) IsLoggedIn(ctx context.Context) bool {
+	return b.oracleFor(nil).Evaluate(ctx, b).Success
+}
+
+func (b *Browser) findElement(ctx context.Context, selector, name string) (*rod.Element, error) {
 	logger := log.WithFields(log.Fields{
 		"selector": selector,
 		"name":     name,
@@ -174,10 +274,11 @@ func (b *Browser) findElement(selector, name string) (*rod.Element, error) {
 	var el *rod.Element
 	var err error
 
-	// Wait for element with retry and fallback
-	for i := 0; i < 3; i++ {
-		// Try XPath first
-		el, err = b.page.ElementX(selector)
+	// Wait for element until the element timeout, backing off between polls
+	deadline := time.Now().Add(b.GetTimeouts().Element)
+	interval := PollInterval
+	for i := 1; ; i++ {
+		el, err = b.resolve(ctx, selector)
 
 		if err == nil && el != nil {
 			if visible, _ := el.Visible(); visible {
@@ -186,9 +287,15 @@ func (b *Browser) findElement(selector, name string) (*rod.Element, error) {
 			}
 		}
 
-		if i < 2 {
-			time.Sleep(BackoffFactor * time.Duration(1<<uint(i)))
-			logger.WithField("attempt", i+1).Debug("Element not found, retrying...")
+		if time.Now().Add(interval).After(deadline) {
+			break
+		}
+		if err = sleep(ctx, interval); err != nil {
+			return nil, err
+		}
+		logger.WithField("attempt", i).Debug("Element not found, retrying...")
+		if interval *= 2; interval > BackoffFactor {
+			interval = BackoffFactor
 		}
 	}
 
@@ -196,16 +303,16 @@ func (b *Browser) findElement(selector, name string) (*rod.Element, error) {
 		return nil, nil
 	}
 
-	return nil, fmt.Errorf("element %s not found or not visible after retries", name)
+	return nil, fmt.Errorf("%w: %s not found or not visible within %v", ErrElementNotFound, name, b.GetTimeouts().Element)
 }
 
-func (b *Browser) performLogin(selector *Selector, username, password string) error {
+func (b *Browser) performLogin(ctx context.Context, selector *Selector, username, password string) error {
 	start := time.Now()
 	logger := log.WithFields(log.Fields{
 		"action":   "perform_login",
 		"username": username,
 		"password": password,
-		"url":      b.page.MustInfo().URL,
+		"url":      b.currentURL(),
 	})
 
 	logger.Debug("Starting form interaction")
@@ -213,30 +320,51 @@ func (b *Browser) performLogin(selector *Selector, username, password string) er
 		return fmt.Errorf("selector cannot be nil")
 	}
 
+	b.mu.Lock()
+	b.matched = make(map[string]string)
+	b.mu.Unlock()
+
 	var err error
 
 	// todo: Find UserInput elements
 	var userEL *rod.Element
-	if userEL, err = b.findElement(selector.UserInput, "username input"); err != nil {
+	if userEL, err = b.findField(ctx, selector, FieldUserInput, "username input"); err != nil {
 		return err
 	}
 	if err = userEL.Input(username); err != nil {
 		return fmt.Errorf("failed to input username: %v", err)
 	}
-	time.Sleep(500 * time.Millisecond)
+	if err = sleep(ctx, 500*time.Millisecond); err != nil {
+		return err
+	}
 
 	// todo: Find PasswordInput elements
 	var passEl *rod.Element
-	if passEl, err = b.findElement(selector.PasswordInput, "password input"); err != nil {
+	if passEl, err = b.findField(ctx, selector, FieldPasswordInput, "password input"); err != nil {
 		return err
 	}
 	if err = passEl.Input(password); err != nil {
 		return fmt.Errorf("failed to input password: %v", err)
 	}
-	time.Sleep(500 * time.Millisecond)
+	if err = sleep(ctx, 500*time.Millisecond); err != nil {
+		return err
+	}
 
 	// todo: Find CheckBox elements
-	if _, err = b.page.Eval(`() => {
+	if selector.RememberMe != "" {
+		// 配置了选择器时按同一套规则解析，找不到也不影响登录
+		for _, candidate := range selector.Candidates(FieldRememberMe) {
+			checkEL, findErr := b.resolve(ctx, candidate)
+			if findErr != nil {
+				continue
+			}
+			if _, err = checkEL.Eval(`() => { if (!this.checked) this.click(); return true; }`); err != nil {
+				return err
+			}
+			b.recordMatch(FieldRememberMe, candidate)
+			break
+		}
+	} else if _, err = b.query(ctx).Eval(`() => {
 		const checkbox = document.querySelector('input[type="checkbox"]');
 		console.log(checkbox);
 		if (checkbox) {
@@ -247,7 +375,9 @@ func (b *Browser) performLogin(selector *Selector, username, password string) er
 	}`); err != nil {
 		return err
 	}
-	time.Sleep(500 * time.Millisecond)
+	if err = sleep(ctx, 500*time.Millisecond); err != nil {
+		return err
+	}
 
 	// todo: Find captcha elements
 	if b.captchaHandler != nil {
@@ -256,18 +386,18 @@ func (b *Browser) performLogin(selector *Selector, username, password string) er
 			logger.Debug("Handling captcha challenge")
 
 			// Create context with timeout for OCR
-			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			ocrCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 			defer cancel()
 
 			var captchaText string
-			captchaText, err = b.captchaHandler.HandleCaptcha(ctx, selector)
+			captchaText, err = b.captchaHandler.HandleCaptcha(ocrCtx, selector)
 			if err != nil {
-				return fmt.Errorf("failed to handle captcha: %w", err)
+				return fmt.Errorf("%w: %v", ErrCaptchaFailed, err)
 			}
 
 			// Find and input captcha text
 			var captchaEl *rod.Element
-			if captchaEl, err = b.findElement(selector.CaptchaInput, "captcha input"); err != nil {
+			if captchaEl, err = b.findField(ctx, selector, FieldCaptchaInput, "captcha input"); err != nil {
 				return fmt.Errorf("failed to find captcha input: %w", err)
 			}
 
@@ -275,7 +405,9 @@ func (b *Browser) performLogin(selector *Selector, username, password string) er
 				return fmt.Errorf("failed to input captcha: %w", err)
 			}
 
-			time.Sleep(500 * time.Millisecond)
+			if err = sleep(ctx, 500*time.Millisecond); err != nil {
+				return err
+			}
 			logger.WithField("captcha_text", captchaText).Debug("Captcha input completed")
 		} else {
 			logger.Debug("No captcha elements found, proceeding without captcha")
@@ -284,40 +416,18 @@ func (b *Browser) performLogin(selector *Selector, username, password string) er
 
 	// todo: Find LoginBtn elements
 	var btnEL *rod.Element
-	if btnEL, err = b.findElement(selector.LoginBtn, "login button"); err != nil {
+	if btnEL, err = b.findField(ctx, selector, FieldLoginBtn, "login button"); err != nil {
 		return err
 	}
 
-	//if err = btnEL.Click(proto.InputMouseButtonLeft, 1); err != nil {
-	//	return fmt.Errorf("failed to click login button: %v", err)
-	//}
-	//if _, err = btnEL.Eval(`(element) => {
-	//		console.log(element)
-	//		const el = document.querySelector(element.Object.description);
-	//		el.click();
-	//	}`, btnEL); err != nil {
-	//	return fmt.Errorf("failed to click login button: %v", err)
-	//}
-	if _, err = btnEL.Eval(`(xpath) => {
-			const xpathExpression = xpath;
-			const result = document.evaluate(
-				xpathExpression,
-				document,
-				null,
-				XPathResult.FIRST_ORDERED_NODE_TYPE,
-				null
-			);
-	
-			const element = result.singleNodeValue; // 获取匹配的节点
-			if (element) {
-				element.click()
-				return true;
-			}
-			return false;
-		}`, selector.LoginBtn); err != nil {
+	// 直接点击已定位的元素，各类选择器共用同一套解析结果
+	b.markClick()
+	if _, err = btnEL.Eval(`() => { this.click(); return true; }`); err != nil {
 		return fmt.Errorf("failed to click login button: %v", err)
 	}
-	time.Sleep(500 * time.Millisecond)
+	if err = sleep(ctx, 500*time.Millisecond); err != nil {
+		return err
+	}
 
 	// Brief wait for form submission
 	logger.WithField("duration", time.Since(start)).Debug("Login form submitted")
@@ -332,7 +442,7 @@ func (b *Browser) Login(ctx context.Context, selector *Selector, username, passw
 		"action":   "login_attempt",
 		"username": username,
 		"password": password,
-		"url":      b.page.MustInfo().URL,
+		"url":      b.currentURL(),
 	})
 
 	logger.Debug("Testing credentials")
@@ -340,21 +450,40 @@ func (b *Browser) Login(ctx context.Context, selector *Selector, username, passw
 	// 兼容SDK调度
 	if selector == nil {
 		var err error
-		selector, err = b.DetectFormSelectors()
+		selector, err = b.DetectFormSelectors(ctx)
 		if err != nil {
 			return fmt.Errorf("failed to detect selectors: %w", err)
 		}
 	}
 
 	// 每次任务登录的上下文
-	loginCtx, cancel := context.WithTimeout(ctx, time.Duration(10)*time.Second)
+	loginTimeout := b.GetTimeouts().Login
+	loginCtx, cancel := context.WithTimeout(ctx, loginTimeout)
 	defer cancel()
 
+	// 提交前已经存在的错误提示（如上一次尝试留下的）不作为本次的失败依据
+	b.mu.Lock()
+	b.submittedAt = time.Time{}
+	b.mu.Unlock()
+
+	oracle := b.oracleFor(selector)
+	stale := oracle.Evaluate(loginCtx, b)
+	b.setVerdict(Verdict{})
+
+	b.mu.Lock()
+	b.username, b.password = username, password
+	b.mu.Unlock()
+	b.startCapture(loginCtx)
+
 	// 登录操作
-	if err := b.performLogin(selector, username, password); err != nil {
+	if err := b.performLogin(loginCtx, selector, username, password); err != nil {
 		return err
 	}
 
+	b.mu.Lock()
+	b.submittedAt = time.Now()
+	b.mu.Unlock()
+
 	// 轮询等待结果
 	ticker := time.NewTicker(200 * time.Millisecond)
 	defer ticker.Stop()
@@ -362,27 +491,40 @@ func (b *Browser) Login(ctx context.Context, selector *Selector, username, passw
 	for {
 		select {
 		case <-loginCtx.Done():
-			return fmt.Errorf("login timeout after %v", 10)
-		case <-ticker.C:
-			// Check for error messages first
-			if errEL, err := b.page.Element("div[role='alert']"); err == nil && errEL != nil {
-				if visible, _ := errEL.Visible(); visible {
-					if text, err := errEL.Text(); err == nil && text != "" {
-						logger.WithField("error", text).Debug("Found error message")
-						return fmt.Errorf("login error: %s", text)
-					}
-				}
+			if ctx.Err() != nil {
+				return ctx.Err()
 			}
-
-			// Check login status
-			if b.IsLoggedIn() {
-				logger.WithField("duration", time.Since(start)).Info("Login successful")
+			return fmt.Errorf("%w after %v", ErrLoginTimeout, loginTimeout)
+		case <-ticker.C:
+			verdict := oracle.Evaluate(loginCtx, b)
+			if verdict.Success {
+				b.setVerdict(verdict)
+				logger.WithFields(log.Fields{
+					"duration": time.Since(start),
+					"rule":     verdict.Rule,
+				}).Info("Login successful")
 				return nil
 			}
+			if verdict.Failed && !(stale.Failed && stale.Rule == verdict.Rule) {
+				b.setVerdict(verdict)
+				logger.WithField("rule", verdict.Rule).Debug("Login rejected")
+				return fmt.Errorf("%w: %s", ErrLoginRejected, verdict.Rule)
+			}
 		}
 	}
 }
 
+// setVerdict
+// @Description: 记录本次登录的判定结果
+// @receiver b
+// @param v
+func (b *Browser) setVerdict(v Verdict) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.verdict = v
+}
+
 func (b *Browser) Navigate(ctx context.Context, url string) error {
 	var err error
 
@@ -393,8 +535,8 @@ func (b *Browser) Navigate(ctx context.Context, url string) error {
 	logger.Debug("Starting navigation")
 
 	// Clean up previous session
-	if b.page != nil {
-		if err = b.page.Close(); err != nil {
+	if page := b.GetPage(); page != nil {
+		if err = page.Close(); err != nil {
 			logger.WithError(err).Debug("Error during cleanup")
 		}
 	}
@@ -402,12 +544,42 @@ func (b *Browser) Navigate(ctx context.Context, url string) error {
 	// Create new browser page
 	var page *rod.Page
 	loginURL := strings.TrimRight(url, "/")
-	page, err = b.browser.Page(proto.TargetCreateTarget{URL: loginURL})
+	page, err = b.browser.Page(proto.TargetCreateTarget{})
 	if err != nil {
-		return fmt.Errorf("page creation failed: %w", err)
+		return fmt.Errorf("%w: page creation failed: %v", ErrNavigationFailed, err)
+	}
+
+	// 页面本身不绑定导航上下文，后续操作各自传入上下文
+	eventsCtx, stopEvents := context.WithCancel(context.Background())
+	b.mu.Lock()
+	if b.stopEvents != nil {
+		b.stopEvents()
+	}
+	b.page = page
+	b.lastStatus = 0
+	b.lastHeaders = nil
+	b.stopEvents = stopEvents
+	headers := b.headers
+	b.mu.Unlock()
+
+	// 记录主文档的HTTP状态码，供 status 规则判定；开启网络观察时记录登录请求
+	go page.Context(eventsCtx).EachEvent(b.networkEvents(page)...)()
+	page = page.Context(ctx)
+
+	// Extra headers must be set before the first request
+	if len(headers) > 0 {
+		var dict []string
+		for key, value := range headers {
+			dict = append(dict, key, value)
+		}
+		if _, err = page.SetExtraHeaders(dict); err != nil {
+			return fmt.Errorf("set extra headers failed: %w", err)
+		}
 	}
 
-	b.page = page.Context(ctx)
+	if err = page.Navigate(loginURL); err != nil {
+		return fmt.Errorf("%w: %v", ErrNavigationFailed, err)
+	}
 
 	// Create error channel for timeout handling
 	errChan := make(chan error, 1)
@@ -424,7 +596,7 @@ func (b *Browser) Navigate(ctx context.Context, url string) error {
 		//}
 
 		// Wait for initial page load
-		if err = b.page.WaitLoad(); err != nil {
+		if err := page.WaitLoad(); err != nil {
 			errChan <- fmt.Errorf("page load failed: %w", err)
 			return
 		}
@@ -436,16 +608,35 @@ func (b *Browser) Navigate(ctx context.Context, url string) error {
 	select {
 	case err = <-errChan:
 		if err != nil {
-			return fmt.Errorf("navigation failed: %w", err)
+			return fmt.Errorf("%w: %v", ErrNavigationFailed, err)
 		}
 	case <-ctx.Done():
-		return fmt.Errorf("navigate timed out after 15 seconds")
+		return fmt.Errorf("%w: timed out", ErrNavigationFailed)
 	}
 
 	logger.Debug("Navigation completed successfully")
 	return nil
 }
 
+// PageText
+// @Description: 当前页面的可见文本，开启网络观察时附带最近一次响应体，用于判断失败原因
+// @receiver b
+// @param ctx
+// @return string
+func (b *Browser) PageText(ctx context.Context) string {
+	var text string
+	if res, err := b.query(ctx).Eval(`() => document.body ? document.body.innerText : ''`); err == nil {
+		text = res.Value.Str()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.observer != nil && b.lastResponse != "" {
+		text += "\n" + b.lastResponse
+	}
+	return text
+}
+
 // GetHtmlContent
 // @Description: 获取当前rod.Page对象的页面信息
 // @receiver b
@@ -472,89 +663,3 @@ func (b *Browser) GetPage() *rod.Page {
 
 	return b.page
 }
-
-// handleNetworkResponse
-// @Description: 检测网络请求
-// @receiver b
-// @param ctx
-func (b *Browser) handleNetworkResponse(ctx *rod.Hijack) {
-	logger := log.WithField("action", "handle_network_response")
-
-	b.mu.Lock()
-	defer b.mu.Unlock()
-
-	// Load response and get status code
-	ctx.MustLoadResponse()
-	if resp := ctx.Response; resp != nil {
-		// Get status code from response
-		if status := ctx.Response.Payload().ResponseCode; status != 0 {
-			b.lastStatus = status
-			logger.WithFields(log.Fields{
-				"status": b.lastStatus,
-				"url":    ctx.Request.URL().String(),
-			}).Debug("Received response status")
-		}
-
-		// Check response headers for auth tokens and session identifiers
-		respHeaders := resp.Headers()
-		for key, values := range respHeaders {
-			if len(values) == 0 {
-				continue
-			}
-
-			lowerKey := strings.ToLower(key)
-			// Check for auth tokens
-			if strings.Contains(lowerKey, "token") ||
-				strings.Contains(lowerKey, "auth") ||
-				strings.Contains(lowerKey, "session") ||
-				strings.Contains(lowerKey, "sid") ||
-				strings.Contains(lowerKey, "jwt") {
-				b.authTokens[key] = values[0]
-				logger.WithFields(log.Fields{
-					"header": key,
-					"value":  values[0],
-					"type":   "auth_token",
-				}).Debug("Found authentication token in response header")
-			}
-			// Check for cookies with auth tokens
-			if lowerKey == "set-cookie" {
-				for _, cookie := range values {
-					if strings.Contains(strings.ToLower(cookie), "token") ||
-						strings.Contains(strings.ToLower(cookie), "auth") ||
-						strings.Contains(strings.ToLower(cookie), "session") ||
-						strings.Contains(strings.ToLower(cookie), "sid") ||
-						strings.Contains(strings.ToLower(cookie), "jwt") {
-						b.authTokens["cookie_"+key] = cookie
-						logger.WithFields(log.Fields{
-							"cookie": cookie,
-							"type":   "auth_cookie",
-						}).Debug("Found authentication cookie")
-					}
-				}
-			}
-		}
-
-		// Handle response body based on content type
-		contentType := respHeaders["Content-Type"]
-		if len(contentType) > 0 {
-			body := resp.Body()
-			b.lastResponse = string(body)
-			logger.WithFields(log.Fields{
-				"content_type": contentType[0],
-				"body_length":  len(b.lastResponse),
-			}).Debug("Stored response body")
-		}
-
-		// Handle redirects
-		if b.lastStatus == 302 || b.lastStatus == 301 {
-			location := respHeaders["Location"]
-			if len(location) > 0 {
-				logger.WithFields(log.Fields{
-					"status":   b.lastStatus,
-					"location": location[0],
-					"type":     "redirect",
-				}).Debug("Found redirect response")
-			}
-		}
-	}
-}
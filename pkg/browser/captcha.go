@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"github.com/go-rod/rod/lib/proto"
-	"os"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
@@ -48,7 +47,7 @@ func (h *CaptchaHandler) HandleCaptcha(ctx context.Context, selector *Selector)
 
 	logger := log.WithField("action", "handle_captcha")
 
-	imgEL, err := h.browser.findElement(selector.CaptchaImg, "captcha image")
+	imgEL, err := h.browser.findField(ctx, selector, FieldCaptchaImg, "captcha image")
 	if err != nil {
 		return "", fmt.Errorf("captcha image not found: %w", err)
 	}
@@ -58,11 +57,6 @@ func (h *CaptchaHandler) HandleCaptcha(ctx context.Context, selector *Selector)
 		return "", fmt.Errorf("captcha image data failed: %w", err)
 	}
 
-	err = os.WriteFile("1.png", data, 0644)
-	if err != nil {
-		return "", fmt.Errorf("captcha image data failed: %w", err)
-	}
-
 	base64Data := base64.StdEncoding.EncodeToString(data)
 	if len(base64Data) < ocr.MinImageSize || len(base64Data) > ocr.MaxImageSize {
 		return "", ocr.ErrInvalidImage
@@ -81,3 +75,26 @@ func (h *CaptchaHandler) HandleCaptcha(ctx context.Context, selector *Selector)
 	logger.WithField("result", result).Info("Captcha recognized")
 	return result, nil
 }
+
+// captchaSelectors 常见验证码元素，用于发现未在选择器中配置的验证码
+const captchaSelectors = `img[src*="captcha" i], img[id*="captcha" i], img[class*="captcha" i], ` +
+	`input[name*="captcha" i], input[id*="captcha" i], input[name*="verifycode" i], input[name*="vcode" i], ` +
+	`iframe[src*="recaptcha"], iframe[src*="hcaptcha"], [class*="geetest"], [id*="nc_1_wrapper"]`
+
+// HasCaptcha
+// @Description: 页面上是否有可见的验证码元素
+// @receiver b
+// @param ctx
+// @return bool
+func (b *Browser) HasCaptcha(ctx context.Context) bool {
+	els, err := b.query(ctx).Elements(captchaSelectors)
+	if err != nil {
+		return false
+	}
+	for _, el := range els {
+		if visible, _ := el.Visible(); visible {
+			return true
+		}
+	}
+	return false
+}
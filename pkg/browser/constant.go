@@ -5,4 +5,16 @@ import "time"
 const (
 	BackoffFactor = time.Second
 	MaxRetries    = 3
+
+	// 元素轮询的初始间隔，之后按指数退避，最大不超过 BackoffFactor
+	PollInterval = 250 * time.Millisecond
+
+	DefaultNavigationTimeout = 10 * time.Second
+	DefaultElementTimeout    = 5 * time.Second
+	DefaultLoginTimeout      = 10 * time.Second
+
+	// 提交后等待页面稳定再与失败基线比较
+	BaselineSettle = 2 * time.Second
+	// 与失败基线的相似度不低于该值视为失败
+	DefaultBaselineThreshold = 0.85
 )
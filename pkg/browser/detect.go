@@ -0,0 +1,285 @@
+package browser
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/go-rod/rod"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed detect.yaml
+var defaultDetectRules []byte
+
+// Pattern
+// @Description: 探测词典中的一个模式
+type Pattern struct {
+	Selector string `yaml:"selector" json:"selector"`
+	Weight   int    `yaml:"weight" json:"weight"`
+}
+
+func (p *Pattern) UnmarshalYAML(node *yaml.Node) error {
+	// 只写选择器时权重为 1
+	if node.Kind == yaml.ScalarNode {
+		*p = Pattern{Selector: node.Value, Weight: 1}
+		return nil
+	}
+	type plain Pattern
+	var v plain
+	if err := node.Decode(&v); err != nil {
+		return err
+	}
+	*p = Pattern(v)
+	return nil
+}
+
+// Group
+// @Description: 一个语言分组（如 common、zh、en、ja）下的模式
+type Group struct {
+	Lang     string
+	Patterns []Pattern
+}
+
+// groups 按文件中的顺序保存语言分组
+type groups []Group
+
+func (g *groups) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: expected language groups", node.Line)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		group := Group{Lang: node.Content[i].Value}
+		if err := node.Content[i+1].Decode(&group.Patterns); err != nil {
+			return err
+		}
+		*g = append(*g, group)
+	}
+	return nil
+}
+
+// DetectRules
+// @Description: 表单探测词典，字段名与选择器字段一致
+type DetectRules struct {
+	Fields map[string][]Group
+
+	ordered map[string][]Pattern
+}
+
+// DefaultDetectRules
+// @Description: 内置的探测词典
+// @return *DetectRules
+func DefaultDetectRules() *DetectRules {
+	r, err := parseDetectRules(defaultDetectRules)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in detect rules: %v", err))
+	}
+	r.order()
+	return r
+}
+
+// LoadDetectRules
+// @Description: 在内置词典的基础上合并文件中的模式：已有的选择器只更新权重，新的选择器追加到对应字段和语言分组
+// @param path 为空时只使用内置词典
+// @return *DetectRules
+// @return error
+func LoadDetectRules(path string) (*DetectRules, error) {
+	r := DefaultDetectRules()
+	if path == "" {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read detect rules: %w", err)
+	}
+	extra, err := parseDetectRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse detect rules %s: %w", path, err)
+	}
+	for _, field := range SelectorFields {
+		for _, g := range extra.Fields[field] {
+			for _, p := range g.Patterns {
+				r.merge(field, g.Lang, p)
+			}
+		}
+	}
+	r.order()
+	return r, nil
+}
+
+func parseDetectRules(data []byte) (*DetectRules, error) {
+	var raw map[string]groups
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	r := &DetectRules{Fields: make(map[string][]Group)}
+	for field, list := range raw {
+		if !knownField(field) {
+			return nil, fmt.Errorf("unknown field %q, expected one of %s", field, strings.Join(SelectorFields, ", "))
+		}
+		for _, g := range list {
+			for _, p := range g.Patterns {
+				if err := p.validate(); err != nil {
+					return nil, fmt.Errorf("%s.%s: %w", field, g.Lang, err)
+				}
+			}
+		}
+		r.Fields[field] = list
+	}
+	return r, nil
+}
+
+func knownField(name string) bool {
+	for _, field := range SelectorFields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Pattern) validate() error {
+	if strings.TrimSpace(p.Selector) == "" {
+		return fmt.Errorf("empty selector")
+	}
+	if p.Weight < 0 {
+		return fmt.Errorf("selector %q: weight must not be negative", p.Selector)
+	}
+	switch ParseSelector(p.Selector).Kind {
+	case KindCSS, KindXPath:
+		return nil
+	default:
+		return fmt.Errorf("selector %q: only css and xpath selectors are supported", p.Selector)
+	}
+}
+
+// merge
+// @Description: 合并一个模式，选择器已存在时只更新权重
+// @receiver r
+// @param field
+// @param lang
+// @param p
+func (r *DetectRules) merge(field, lang string, p Pattern) {
+	list := r.Fields[field]
+	for i := range list {
+		for j := range list[i].Patterns {
+			if list[i].Patterns[j].Selector == p.Selector {
+				list[i].Patterns[j].Weight = p.Weight
+				return
+			}
+		}
+	}
+	for i := range list {
+		if list[i].Lang == lang {
+			list[i].Patterns = append(list[i].Patterns, p)
+			return
+		}
+	}
+	r.Fields[field] = append(list, Group{Lang: lang, Patterns: []Pattern{p}})
+}
+
+// order 按权重从高到低排列各字段的模式，权重相同时保持出现顺序，去掉权重为 0 和重复的选择器
+func (r *DetectRules) order() {
+	r.ordered = make(map[string][]Pattern, len(r.Fields))
+	for field, list := range r.Fields {
+		seen := make(map[string]bool)
+		var patterns []Pattern
+		for _, g := range list {
+			for _, p := range g.Patterns {
+				if p.Weight == 0 || seen[p.Selector] {
+					continue
+				}
+				seen[p.Selector] = true
+				patterns = append(patterns, p)
+			}
+		}
+		sort.SliceStable(patterns, func(i, j int) bool {
+			return patterns[i].Weight > patterns[j].Weight
+		})
+		r.ordered[field] = patterns
+	}
+}
+
+// Patterns
+// @Description: 字段的全部模式，按尝试顺序排列
+// @receiver r
+// @param field
+// @return []Pattern
+func (r *DetectRules) Patterns(field string) []Pattern {
+	return r.ordered[field]
+}
+
+// PageCandidates
+// @Description: 词典中字段的全部选择器，按尝试顺序排列，用于没有表单可用时在整个页面中查找
+// @receiver b
+// @param field
+// @return []string
+func (b *Browser) PageCandidates(field string) []string {
+	var list []string
+	for _, p := range b.rules().Patterns(field) {
+		list = append(list, p.Selector)
+	}
+	return list
+}
+
+// scope 可以在其中查找元素的页面或表单
+type scope interface {
+	Element(selector string) (*rod.Element, error)
+	ElementX(xpath string) (*rod.Element, error)
+	Elements(selector string) (rod.Elements, error)
+	ElementsX(xpath string) (rod.Elements, error)
+}
+
+// find
+// @Description: 在页面或表单内查找第一个匹配的元素
+// @receiver p
+// @param s
+// @return *rod.Element
+// @return error
+func (p Pattern) find(s scope) (*rod.Element, error) {
+	q := ParseSelector(p.Selector)
+	if q.Kind == KindXPath {
+		return s.ElementX(q.Value)
+	}
+	return s.Element(q.Value)
+}
+
+// findAll
+// @Description: 在页面或表单内查找全部匹配的元素
+// @receiver p
+// @param s
+// @return rod.Elements
+// @return error
+func (p Pattern) findAll(s scope) (rod.Elements, error) {
+	q := ParseSelector(p.Selector)
+	if q.Kind == KindXPath {
+		return s.ElementsX(q.Value)
+	}
+	return s.Elements(q.Value)
+}
+
+// SetDetectRules
+// @Description: 设置表单探测词典，未设置时使用内置词典
+// @receiver b
+// @param r
+func (b *Browser) SetDetectRules(r *DetectRules) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.detectRules = r
+}
+
+// rules 当前使用的探测词典
+func (b *Browser) rules() *DetectRules {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.detectRules == nil {
+		b.detectRules = DefaultDetectRules()
+	}
+	return b.detectRules
+}
@@ -0,0 +1,12 @@
+package browser
+
+import "errors"
+
+var (
+	ErrNavigationFailed = errors.New("navigation failed")
+	ErrElementNotFound  = errors.New("element not found")
+	ErrCaptchaFailed    = errors.New("captcha failed")
+	ErrLoginRejected    = errors.New("login rejected")
+	ErrLoginTimeout     = errors.New("login timed out")
+	ErrFormNotFound     = errors.New("login form not found")
+)
@@ -0,0 +1,90 @@
+package browser
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxFeatureHTML 参与指纹匹配的 HTML 最大长度
+const maxFeatureHTML = 512 * 1024
+
+// PageFeatures
+// @Description: 用于产品指纹识别的页面特征
+type PageFeatures struct {
+	URL     string            `json:"url"`
+	Title   string            `json:"title"`
+	HTML    string            `json:"-"`
+	Headers map[string]string `json:"headers,omitempty"` // 主文档响应头，键为小写
+	Scripts []string          `json:"scripts,omitempty"` // 外部脚本地址
+	Favicon []byte            `json:"-"`                 // 图标原始内容，未请求或获取失败时为空
+}
+
+// featuresJS 页面标题、HTML、脚本地址与图标地址
+const featuresJS = `() => {
+	const icon = document.querySelector("link[rel~='icon'], link[rel='shortcut icon']");
+	return {
+		title: document.title || '',
+		html: document.documentElement ? document.documentElement.outerHTML : '',
+		scripts: Array.from(document.scripts).map(s => s.src).filter(Boolean),
+		icon: icon && icon.href ? icon.href : new URL('/favicon.ico', location.href).href,
+	};
+}`
+
+// faviconJS 在页面中请求图标，返回 base64
+const faviconJS = `async (href) => {
+	const res = await fetch(href, { credentials: 'include' });
+	if (!res.ok) return '';
+	const bytes = new Uint8Array(await res.arrayBuffer());
+	let binary = '';
+	for (const b of bytes) binary += String.fromCharCode(b);
+	return btoa(binary);
+}`
+
+// Features
+// @Description: 收集当前页面的指纹特征
+// @receiver b
+// @param ctx
+// @param favicon 是否请求图标
+// @return *PageFeatures
+// @return error
+func (b *Browser) Features(ctx context.Context, favicon bool) (*PageFeatures, error) {
+	page := b.query(ctx)
+
+	res, err := page.Eval(featuresJS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect page features: %w", err)
+	}
+
+	f := &PageFeatures{
+		URL:     b.currentURL(),
+		Title:   res.Value.Get("title").Str(),
+		HTML:    res.Value.Get("html").Str(),
+		Headers: make(map[string]string),
+	}
+	if len(f.HTML) > maxFeatureHTML {
+		f.HTML = f.HTML[:maxFeatureHTML]
+	}
+	for _, item := range res.Value.Get("scripts").Arr() {
+		f.Scripts = append(f.Scripts, item.Str())
+	}
+
+	b.mu.Lock()
+	for name, value := range b.lastHeaders {
+		f.Headers[strings.ToLower(name)] = value.String()
+	}
+	b.mu.Unlock()
+
+	if favicon {
+		icon := res.Value.Get("icon").Str()
+		if data, err := page.Eval(faviconJS, icon); err != nil {
+			log.WithError(err).WithField("icon", icon).Debug("Failed to fetch favicon")
+		} else if raw, err := base64.StdEncoding.DecodeString(data.Value.Str()); err == nil {
+			f.Favicon = raw
+		}
+	}
+	return f, nil
+}
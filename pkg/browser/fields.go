@@ -0,0 +1,347 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// 选择器字段名，与选择器文件中的键一致
+const (
+	FieldUserInput     = "userInput"
+	FieldPasswordInput = "passwordInput"
+	FieldLoginBtn      = "loginBtn"
+	FieldRememberMe    = "rememberMe"
+	FieldCaptchaInput  = "captchaInput"
+	FieldCaptchaImg    = "captchaImg"
+)
+
+// SelectorFields 全部选择器字段，按登录时的使用顺序排列
+var SelectorFields = []string{
+	FieldUserInput,
+	FieldPasswordInput,
+	FieldLoginBtn,
+	FieldRememberMe,
+	FieldCaptchaInput,
+	FieldCaptchaImg,
+}
+
+// field
+// @Description: 字段名对应的字符串字段
+// @receiver s
+// @param name
+// @return *string 未知字段返回 nil
+func (s *Selector) field(name string) *string {
+	switch name {
+	case FieldUserInput:
+		return &s.UserInput
+	case FieldPasswordInput:
+		return &s.PasswordInput
+	case FieldLoginBtn:
+		return &s.LoginBtn
+	case FieldRememberMe:
+		return &s.RememberMe
+	case FieldCaptchaInput:
+		return &s.CaptchaInput
+	case FieldCaptchaImg:
+		return &s.CaptchaImg
+	}
+	return nil
+}
+
+// Candidates
+// @Description: 字段的候选选择器，按尝试顺序返回
+// @receiver s
+// @param name 字段名
+// @return []string
+func (s *Selector) Candidates(name string) []string {
+	if list := s.Fallbacks[name]; len(list) > 0 {
+		return list
+	}
+	if f := s.field(name); f != nil && *f != "" {
+		return []string{*f}
+	}
+	return nil
+}
+
+// SetCandidates
+// @Description: 设置字段的候选选择器，第一个候选同时写入字符串字段
+// @receiver s
+// @param name 字段名
+// @param list
+func (s *Selector) SetCandidates(name string, list []string) {
+	f := s.field(name)
+	if f == nil {
+		return
+	}
+
+	var cleaned []string
+	for _, item := range list {
+		if item = strings.TrimSpace(item); item != "" {
+			cleaned = append(cleaned, item)
+		}
+	}
+
+	*f = ""
+	delete(s.Fallbacks, name)
+	if len(cleaned) == 0 {
+		return
+	}
+	*f = cleaned[0]
+	if len(cleaned) > 1 {
+		if s.Fallbacks == nil {
+			s.Fallbacks = make(map[string][]string)
+		}
+		s.Fallbacks[name] = cleaned
+	}
+}
+
+// 判定规则相关的键，与选择器文件中的键一致
+const (
+	FieldSuccessIndicators = "successIndicators"
+	FieldErrorIndicators   = "errorIndicators"
+	FieldIndicatorMatch    = "indicatorMatch"
+)
+
+var indicatorFields = []string{FieldSuccessIndicators, FieldErrorIndicators, FieldIndicatorMatch}
+
+// decodeIndicators
+// @Description: 解析判定规则相关的键，value 为 *yaml.Node 或 json.RawMessage
+// @receiver s
+// @param name
+// @param value
+// @return bool 是否为判定规则相关的键
+// @return error
+func (s *Selector) decodeIndicators(name string, value interface{}) (bool, error) {
+	var target interface{}
+	switch name {
+	case FieldSuccessIndicators:
+		target = &s.SuccessIndicators
+	case FieldErrorIndicators:
+		target = &s.ErrorIndicators
+	case FieldIndicatorMatch:
+		target = &s.IndicatorMatch
+	default:
+		return false, nil
+	}
+
+	var err error
+	switch v := value.(type) {
+	case *yaml.Node:
+		err = v.Decode(target)
+	case json.RawMessage:
+		err = json.Unmarshal(v, target)
+	}
+	return true, err
+}
+
+// UnmarshalYAML
+// @Description: 每个字段接受字符串或字符串列表
+// @receiver s
+// @param node
+// @return error
+func (s *Selector) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: selector must be a mapping", node.Line)
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if ok, err := s.decodeIndicators(key.Value, value); ok || err != nil {
+			if err != nil {
+				return fmt.Errorf("line %d: %s: %w", value.Line, key.Value, err)
+			}
+			continue
+		}
+		if s.field(key.Value) == nil {
+			continue
+		}
+
+		switch value.Kind {
+		case yaml.ScalarNode:
+			s.SetCandidates(key.Value, []string{value.Value})
+		case yaml.SequenceNode:
+			var list []string
+			if err := value.Decode(&list); err != nil {
+				return fmt.Errorf("line %d: %s must be a list of strings", value.Line, key.Value)
+			}
+			s.SetCandidates(key.Value, list)
+		default:
+			return fmt.Errorf("line %d: %s must be a string or a list of strings", value.Line, key.Value)
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON
+// @Description: 每个字段接受字符串或字符串列表
+// @receiver s
+// @param data
+// @return error
+func (s *Selector) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	for _, name := range indicatorFields {
+		if value, ok := raw[name]; ok {
+			if _, err := s.decodeIndicators(name, value); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+
+	for _, name := range SelectorFields {
+		value, ok := raw[name]
+		if !ok {
+			continue
+		}
+		var one string
+		if err := json.Unmarshal(value, &one); err == nil {
+			s.SetCandidates(name, []string{one})
+			continue
+		}
+		var list []string
+		if err := json.Unmarshal(value, &list); err != nil {
+			return fmt.Errorf("%s must be a string or a list of strings", name)
+		}
+		s.SetCandidates(name, list)
+	}
+	return nil
+}
+
+// MarshalJSON
+// @Description: 有多个候选的字段输出为列表，与选择器文件格式保持一致
+// @receiver s
+// @return []byte
+// @return error
+func (s Selector) MarshalJSON() ([]byte, error) {
+	value := func(name string) interface{} {
+		if list := s.Fallbacks[name]; len(list) > 1 {
+			return list
+		}
+		return *s.field(name)
+	}
+
+	return json.Marshal(struct {
+		UserInput     interface{} `json:"userInput"`
+		PasswordInput interface{} `json:"passwordInput"`
+		LoginBtn      interface{} `json:"loginBtn"`
+		RememberMe    interface{} `json:"rememberMe"`
+		CaptchaInput  interface{} `json:"captchaInput"`
+		CaptchaImg    interface{} `json:"captchaImg"`
+
+		SuccessIndicators []*Indicator `json:"successIndicators,omitempty"`
+		ErrorIndicators   []*Indicator `json:"errorIndicators,omitempty"`
+		IndicatorMatch    string       `json:"indicatorMatch,omitempty"`
+	}{
+		UserInput:     value(FieldUserInput),
+		PasswordInput: value(FieldPasswordInput),
+		LoginBtn:      value(FieldLoginBtn),
+		RememberMe:    value(FieldRememberMe),
+		CaptchaInput:  value(FieldCaptchaInput),
+		CaptchaImg:    value(FieldCaptchaImg),
+
+		SuccessIndicators: s.SuccessIndicators,
+		ErrorIndicators:   s.ErrorIndicators,
+		IndicatorMatch:    s.IndicatorMatch,
+	})
+}
+
+// findField
+// @Description: 在元素超时内轮询字段的全部候选选择器，按顺序返回第一个可见的元素，并记录命中的候选
+// @receiver b
+// @param ctx
+// @param selector
+// @param field 字段名
+// @param name 日志中的元素名称
+// @return *rod.Element
+// @return error
+func (b *Browser) findField(ctx context.Context, selector *Selector, field, name string) (*rod.Element, error) {
+	candidates := selector.Candidates(field)
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w: no selector configured for %s", ErrElementNotFound, name)
+	}
+	if len(candidates) == 1 {
+		el, err := b.findElement(ctx, candidates[0], name)
+		if err == nil && el != nil {
+			b.recordMatch(field, candidates[0])
+		}
+		return el, err
+	}
+
+	logger := log.WithFields(log.Fields{
+		"field":      field,
+		"name":       name,
+		"candidates": len(candidates),
+	})
+	logger.Debug("Finding element from candidates")
+
+	deadline := time.Now().Add(b.GetTimeouts().Element)
+	interval := PollInterval
+	for i := 1; ; i++ {
+		for index, candidate := range candidates {
+			if el, err := b.resolve(ctx, candidate); err == nil && el != nil {
+				if visible, _ := el.Visible(); visible {
+					logger.WithFields(log.Fields{
+						"selector": candidate,
+						"index":    index,
+					}).Info("Element found and ready")
+					b.recordMatch(field, candidate)
+					return el, nil
+				}
+			}
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			break
+		}
+		if err := sleep(ctx, interval); err != nil {
+			return nil, err
+		}
+		logger.WithField("attempt", i).Debug("Element not found, retrying...")
+		if interval *= 2; interval > BackoffFactor {
+			interval = BackoffFactor
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s not found or not visible within %v (tried %d candidates)",
+		ErrElementNotFound, name, b.GetTimeouts().Element, len(candidates))
+}
+
+// recordMatch
+// @Description: 记录本次登录中字段命中的候选
+// @receiver b
+// @param field
+// @param candidate
+func (b *Browser) recordMatch(field, candidate string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.matched == nil {
+		b.matched = make(map[string]string)
+	}
+	b.matched[field] = candidate
+}
+
+// MatchedSelectors
+// @Description: 最近一次登录中各字段实际命中的选择器
+// @receiver b
+// @return map[string]string
+func (b *Browser) MatchedSelectors() map[string]string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	matched := make(map[string]string, len(b.matched))
+	for k, v := range b.matched {
+		matched[k] = v
+	}
+	return matched
+}
@@ -0,0 +1,126 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/go-rod/rod"
+	log "github.com/sirupsen/logrus"
+)
+
+// nearestAttr 虚拟容器中与密码框最近的用户名输入框和按钮上的标记，值为字段名
+const nearestAttr = "data-weblogin-nearest"
+
+// virtualFormsJS 为每个可见的密码框向上查找最近的同时包含文本输入框和按钮的祖先元素作为虚拟容器，
+// 只找到文本输入框时退回最近包含文本输入框的祖先；并按布局距离标记容器内最近的用户名输入框和按钮
+const virtualFormsJS = `() => {
+	const maxDepth = 8;
+	const attr = '` + nearestAttr + `';
+	const clickable = "button, input[type='submit'], input[type='button'], input[type='image'], [role='button'], a, [onclick], [class*='btn'], [class*='button']";
+	const visible = el => {
+		const rect = el.getBoundingClientRect();
+		const style = getComputedStyle(el);
+		return rect.width > 0 && rect.height > 0 && style.visibility !== 'hidden' && style.display !== 'none';
+	};
+	const textual = el => ['text', 'email', 'tel', 'number'].includes(el.type);
+	const texts = el => Array.from(el.querySelectorAll('input')).filter(i => textual(i) && visible(i));
+	const buttons = (el, pw) => Array.from(el.querySelectorAll(clickable)).filter(b => visible(b) && !b.contains(pw));
+	const center = el => {
+		const rect = el.getBoundingClientRect();
+		return [rect.x + rect.width / 2, rect.y + rect.height / 2];
+	};
+	// 用户名通常在密码框上方或左侧，按钮通常在下方，位置相反的距离加倍
+	const nearest = (list, pw, above) => {
+		const [px, py] = center(pw);
+		let best = null, min = Infinity;
+		for (const el of list) {
+			const [x, y] = center(el);
+			let d = Math.hypot(x - px, y - py);
+			if (above ? y > py + 1 : y < py - 1) d *= 2;
+			if (d < min) {
+				min = d;
+				best = el;
+			}
+		}
+		return best;
+	};
+
+	for (const el of document.querySelectorAll('[' + attr + ']')) el.removeAttribute(attr);
+
+	const containers = [];
+	for (const pw of document.querySelectorAll("input[type='password']")) {
+		if (!visible(pw)) continue;
+
+		let container = null, fallback = null;
+		for (let el = pw.parentElement, depth = 0; el && el !== document.documentElement && depth < maxDepth; el = el.parentElement, depth++) {
+			if (!texts(el).length) continue;
+			fallback = fallback || el;
+			if (buttons(el, pw).length) {
+				container = el;
+				break;
+			}
+		}
+		container = container || fallback;
+		if (!container || containers.includes(container)) continue;
+
+		const user = nearest(texts(container), pw, true);
+		if (user) user.setAttribute(attr, 'userInput');
+		const button = nearest(buttons(container, pw), pw, false);
+		if (button) button.setAttribute(attr, 'loginBtn');
+		containers.push(container);
+	}
+	return containers;
+}`
+
+// candidates
+// @Description: 字段的候选模式：词典中的模式，用户名和登录按钮再加上虚拟容器中按距离标记的元素（权重为 0，最后尝试）
+// @receiver r
+// @param field
+// @return []Pattern
+func (r *DetectRules) candidates(field string) []Pattern {
+	patterns := append([]Pattern(nil), r.Patterns(field)...)
+	if field == FieldUserInput || field == FieldLoginBtn {
+		patterns = append(patterns, Pattern{Selector: fmt.Sprintf("[%s='%s']", nearestAttr, field)})
+	}
+	return patterns
+}
+
+// DetectVirtualForms
+// @Description: 页面没有完整的表单时（如 Vue/React 登录页），以密码框及其附近的输入框和按钮组成虚拟容器，
+// 按与表单相同的方式探测字段并评分，按分数从高到低排列
+// @receiver b
+// @param ctx
+// @param offset 虚拟容器的序号从 offset 开始，与真实表单的序号不重复
+// @return []*FormDesc
+// @return error
+func (b *Browser) DetectVirtualForms(ctx context.Context, offset int) ([]*FormDesc, error) {
+	logger := log.WithField("action", "detect_virtual_forms")
+	page := b.query(ctx)
+
+	containers, err := page.ElementsByJS(rod.Eval(virtualFormsJS))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build virtual forms: %w", err)
+	}
+
+	var candidates []*FormDesc
+	for i, container := range containers {
+		desc, err := b.scoreLoginForm(ctx, container)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			logger.WithError(err).WithField("index", offset+i).Debug("Failed to score virtual form")
+			continue
+		}
+		desc.Index = offset + i
+		desc.Virtual = true
+		candidates = append(candidates, desc)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	logger.WithField("count", len(candidates)).Debug("Virtual forms built")
+	return candidates, nil
+}
@@ -0,0 +1,523 @@
+package browser
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxObservedBody 记录的响应体最大长度
+const maxObservedBody = 64 * 1024
+
+// tokenKeywords 认证令牌相关的关键字（响应头、Cookie 名、JSON 键）
+var tokenKeywords = []string{"token", "auth", "session", "sid", "jwt"}
+
+// Exchange
+// @Description: 登录过程中观察到的一次请求与响应
+type Exchange struct {
+	Method      string            `json:"method"`
+	URL         string            `json:"url"`
+	Status      int               `json:"status"`
+	Location    string            `json:"location,omitempty"`
+	ContentType string            `json:"contentType,omitempty"`
+	Body        string            `json:"-"`
+	Tokens      map[string]string `json:"tokens,omitempty"`
+	PostData    string            `json:"-"`
+	AfterClick  bool              `json:"-"` // 点击登录按钮之后发出的请求
+
+	headers    proto.NetworkHeaders
+	generation int
+}
+
+// networkObserver
+// @Description: 被动监听页面网络事件，记录提交登录后产生的请求，不改变请求本身
+type networkObserver struct {
+	mu         sync.Mutex
+	capturing  bool
+	clicked    bool            // 已点击登录按钮
+	generation int             // 每次开始记录时递增，丢弃上一次登录迟到的响应
+	cookies    map[string]bool // 开始记录前已存在的 Cookie
+	pending    map[proto.NetworkRequestID]*Exchange
+	extra      map[proto.NetworkRequestID]proto.NetworkHeaders
+	exchanges  []*Exchange
+}
+
+// ObserveNetwork
+// @Description: 开启网络观察，登录请求的状态码、JSON 响应和新下发的令牌会作为登录判定依据
+// @receiver b
+func (b *Browser) ObserveNetwork() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.observer == nil {
+		b.observer = &networkObserver{}
+	}
+}
+
+// startCapture
+// @Description: 提交登录前清空记录并开始记录
+// @receiver b
+// @param ctx
+func (b *Browser) startCapture(ctx context.Context) {
+	b.mu.Lock()
+	o := b.observer
+	b.mu.Unlock()
+	if o == nil {
+		return
+	}
+
+	existing := make(map[string]bool)
+	if cookies, err := b.query(ctx).Cookies(nil); err == nil {
+		for _, cookie := range cookies {
+			existing[cookie.Name] = true
+		}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.capturing = true
+	o.clicked = false
+	o.generation++
+	o.cookies = existing
+	o.pending = make(map[proto.NetworkRequestID]*Exchange)
+	o.extra = make(map[proto.NetworkRequestID]proto.NetworkHeaders)
+	o.exchanges = nil
+}
+
+// markClick
+// @Description: 点击登录按钮前调用，之后发出的请求标记为 AfterClick
+// @receiver b
+func (b *Browser) markClick() {
+	b.mu.Lock()
+	o := b.observer
+	b.mu.Unlock()
+	if o == nil {
+		return
+	}
+
+	o.mu.Lock()
+	o.clicked = true
+	o.mu.Unlock()
+}
+
+// networkEvents
+// @Description: 页面网络事件的回调：记录主文档状态码，开启观察时记录登录请求
+// @receiver b
+// @param page
+// @return []interface{}
+func (b *Browser) networkEvents(page *rod.Page) []interface{} {
+	return []interface{}{
+		func(e *proto.NetworkRequestWillBeSent) {
+			o := b.capturingObserver()
+			if o == nil || !observedType(e.Type) {
+				return
+			}
+
+			o.mu.Lock()
+			defer o.mu.Unlock()
+
+			// 重定向沿用同一个 RequestID，先结束上一跳
+			if prev := o.pending[e.RequestID]; prev != nil && e.RedirectResponse != nil {
+				prev.Status = e.RedirectResponse.Status
+				prev.headers = e.RedirectResponse.Headers
+				prev.Location = headerValue(e.RedirectResponse.Headers, "Location")
+				delete(o.pending, e.RequestID)
+				go b.handleNetworkResponse(prev, o.extra[e.RequestID])
+			}
+			o.pending[e.RequestID] = &Exchange{
+				Method:     e.Request.Method,
+				URL:        e.Request.URL,
+				PostData:   e.Request.PostData,
+				AfterClick: o.clicked,
+				generation: o.generation,
+			}
+		},
+		func(e *proto.NetworkResponseReceived) {
+			if e.Type == proto.NetworkResourceTypeDocument && e.FrameID == page.FrameID {
+				b.mu.Lock()
+				b.lastStatus = e.Response.Status
+				b.lastHeaders = e.Response.Headers
+				b.mu.Unlock()
+			}
+
+			if o := b.capturingObserver(); o != nil {
+				o.mu.Lock()
+				if ex := o.pending[e.RequestID]; ex != nil {
+					ex.Status = e.Response.Status
+					ex.ContentType = e.Response.MIMEType
+					ex.headers = e.Response.Headers
+				}
+				o.mu.Unlock()
+			}
+		},
+		func(e *proto.NetworkResponseReceivedExtraInfo) {
+			// Set-Cookie 只出现在 ExtraInfo 中
+			if o := b.capturingObserver(); o != nil {
+				o.mu.Lock()
+				if o.pending[e.RequestID] != nil {
+					o.extra[e.RequestID] = e.Headers
+				}
+				o.mu.Unlock()
+			}
+		},
+		func(e *proto.NetworkLoadingFinished) {
+			o := b.capturingObserver()
+			if o == nil {
+				return
+			}
+
+			o.mu.Lock()
+			ex := o.pending[e.RequestID]
+			delete(o.pending, e.RequestID)
+			o.mu.Unlock()
+			if ex == nil {
+				return
+			}
+
+			// 事件回调中不能阻塞，响应体在单独的协程中获取
+			go func() {
+				if res, err := (proto.NetworkGetResponseBody{RequestID: e.RequestID}).Call(page); err == nil {
+					body := res.Body
+					if res.Base64Encoded {
+						if data, err := base64.StdEncoding.DecodeString(body); err == nil {
+							body = string(data)
+						}
+					}
+					if len(body) > maxObservedBody {
+						body = body[:maxObservedBody]
+					}
+					ex.Body = body
+				}
+
+				o.mu.Lock()
+				extra := o.extra[e.RequestID]
+				o.mu.Unlock()
+				b.handleNetworkResponse(ex, extra)
+			}()
+		},
+	}
+}
+
+// capturingObserver
+// @Description: 正在记录时返回观察器
+// @receiver b
+// @return *networkObserver
+func (b *Browser) capturingObserver() *networkObserver {
+	b.mu.Lock()
+	o := b.observer
+	b.mu.Unlock()
+	if o == nil {
+		return nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if !o.capturing {
+		return nil
+	}
+	return o
+}
+
+func observedType(t proto.NetworkResourceType) bool {
+	return t == proto.NetworkResourceTypeDocument ||
+		t == proto.NetworkResourceTypeXHR ||
+		t == proto.NetworkResourceTypeFetch
+}
+
+// headerValue
+// @Description: 不区分大小写读取响应头
+// @param headers
+// @param key
+// @return string
+func headerValue(headers proto.NetworkHeaders, key string) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, key) {
+			return v.Str()
+		}
+	}
+	return ""
+}
+
+func isTokenName(name string) bool {
+	name = strings.ToLower(name)
+	for _, keyword := range tokenKeywords {
+		if strings.Contains(name, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleNetworkResponse
+// @Description: 解析一次完整的请求响应：状态码、响应头与 Set-Cookie 中的令牌、JSON 响应体中的令牌
+// @receiver b
+// @param ex
+// @param extra ExtraInfo 中的原始响应头
+func (b *Browser) handleNetworkResponse(ex *Exchange, extra proto.NetworkHeaders) {
+	logger := log.WithFields(log.Fields{
+		"action": "handle_network_response",
+		"method": ex.Method,
+		"url":    ex.URL,
+		"status": ex.Status,
+	})
+
+	b.mu.Lock()
+	o := b.observer
+	b.mu.Unlock()
+
+	o.mu.Lock()
+	existing := o.cookies
+	o.mu.Unlock()
+
+	tokens := make(map[string]string)
+
+	// 响应头中的令牌，401 的 WWW-Authenticate 之类不算
+	if ex.Status > 0 && ex.Status < 400 {
+		for key, value := range ex.headers {
+			lower := strings.ToLower(key)
+			if lower == "set-cookie" || !isTokenName(lower) {
+				continue
+			}
+			tokens[key] = value.Str()
+		}
+	}
+
+	// Set-Cookie 中新下发的令牌
+	setCookie := headerValue(extra, "Set-Cookie")
+	if setCookie == "" {
+		setCookie = headerValue(ex.headers, "Set-Cookie")
+	}
+	for _, line := range strings.Split(setCookie, "\n") {
+		pair := strings.SplitN(strings.SplitN(line, ";", 2)[0], "=", 2)
+		name := strings.TrimSpace(pair[0])
+		if len(pair) == 2 && pair[1] != "" && isTokenName(name) && !existing[name] {
+			tokens["cookie_"+name] = pair[1]
+		}
+	}
+
+	// JSON 响应体中的令牌（顶层或 data 下）
+	if body, ok := jsonObject(ex.Body); ok {
+		for _, obj := range []map[string]interface{}{body, nestedObject(body, "data")} {
+			for key, value := range obj {
+				if s, ok := value.(string); ok && s != "" && strings.Contains(strings.ToLower(key), "token") {
+					tokens["json_"+key] = s
+				}
+			}
+		}
+	}
+	if len(tokens) > 0 {
+		ex.Tokens = tokens
+		logger.WithField("tokens", len(tokens)).Debug("Found authentication tokens")
+	}
+	if ex.Location != "" {
+		logger.WithField("location", ex.Location).Debug("Found redirect response")
+	}
+
+	b.mu.Lock()
+	for key, value := range tokens {
+		b.authTokens[key] = value
+	}
+	b.lastResponse = ex.Body
+	b.mu.Unlock()
+
+	o.mu.Lock()
+	if ex.generation == o.generation {
+		o.exchanges = append(o.exchanges, ex)
+	}
+	o.mu.Unlock()
+	logger.Debug("Received response")
+}
+
+// loginExchange
+// @Description: 本次登录的请求，见 SelectLoginExchange
+// @receiver b
+// @return *Exchange
+func (b *Browser) loginExchange() *Exchange {
+	b.mu.Lock()
+	o := b.observer
+	username, password := b.username, b.password
+	b.mu.Unlock()
+	if o == nil {
+		return nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return SelectLoginExchange(o.exchanges, username, password)
+}
+
+// SelectLoginExchange
+// @Description: 从观察到的请求中选出登录请求，只看点击登录按钮后发出的请求（点击前的统计、验证码、用户名校验等不参与判定）：
+// 依次取提交数据或 URL 中包含用户名、包含密码的请求，都没有时（密码可能在前端加密）取第一个非 GET 请求
+// @param exchanges
+// @param username
+// @param password
+// @return *Exchange 没有符合条件的请求时为 nil
+func SelectLoginExchange(exchanges []*Exchange, username, password string) *Exchange {
+	for _, value := range []string{username, password} {
+		for _, ex := range exchanges {
+			if ex.AfterClick && carries(ex, value) {
+				return ex
+			}
+		}
+	}
+	for _, ex := range exchanges {
+		if ex.AfterClick && ex.Method != "GET" {
+			return ex
+		}
+	}
+	return nil
+}
+
+// carries 请求的提交数据或 URL 中是否包含该值（原文、URL 编码或 JSON 转义）
+func carries(ex *Exchange, value string) bool {
+	if value == "" {
+		return false
+	}
+	forms := []string{value, url.QueryEscape(value), url.PathEscape(value)}
+	if quoted, err := json.Marshal(value); err == nil {
+		forms = append(forms, strings.Trim(string(quoted), `"`))
+	}
+	for _, form := range forms {
+		if strings.Contains(ex.PostData, form) || strings.Contains(ex.URL, form) {
+			return true
+		}
+	}
+	return false
+}
+
+// CapturedTokens
+// @Description: 最近一次登录请求中下发的认证令牌
+// @receiver b
+// @return map[string]string
+func (b *Browser) CapturedTokens() map[string]string {
+	ex := b.loginExchange()
+	if ex == nil || len(ex.Tokens) == 0 {
+		return nil
+	}
+	tokens := make(map[string]string, len(ex.Tokens))
+	for k, v := range ex.Tokens {
+		tokens[k] = v
+	}
+	return tokens
+}
+
+func jsonObject(body string) (map[string]interface{}, bool) {
+	body = strings.TrimSpace(body)
+	if !strings.HasPrefix(body, "{") {
+		return nil, false
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &obj); err != nil {
+		return nil, false
+	}
+	return obj, true
+}
+
+func nestedObject(obj map[string]interface{}, key string) map[string]interface{} {
+	nested, _ := obj[key].(map[string]interface{})
+	return nested
+}
+
+// codeKeys JSON 响应中常见的业务状态码字段
+var codeKeys = []string{"code", "errcode", "errCode", "errorCode", "error_code", "ret", "retcode", "status"}
+
+// NetworkVerdict
+// @Description: 按登录请求的 JSON 响应和状态码判定，没有明确结论时返回空判定
+// @param ex
+// @return Verdict
+func NetworkVerdict(ex *Exchange) Verdict {
+	if ex == nil {
+		return Verdict{}
+	}
+	rule := func(s string) string {
+		return fmt.Sprintf("network:%s (%s %s)", s, ex.Method, ex.URL)
+	}
+
+	if body, ok := jsonObject(ex.Body); ok {
+		for _, key := range []string{"success", "ok"} {
+			if v, ok := body[key].(bool); ok {
+				return Verdict{Success: v, Failed: !v, Rule: rule(fmt.Sprintf("%s=%v", key, v))}
+			}
+		}
+		for _, key := range codeKeys {
+			value, ok := body[key]
+			if !ok {
+				continue
+			}
+			var code string
+			switch v := value.(type) {
+			case float64:
+				code = strconv.FormatFloat(v, 'f', -1, 64)
+			case string:
+				code = strings.ToLower(v)
+			default:
+				continue
+			}
+			// 其他数字含义因系统而异（如 {"status":1,"msg":"登录成功"}），不作结论
+			switch code {
+			case "0", "200", "success", "ok":
+				return Verdict{Success: true, Rule: rule(key + "=" + code)}
+			case "401", "403", "fail", "error":
+				return Verdict{Failed: true, Rule: rule(key + "=" + code)}
+			}
+		}
+	}
+
+	if ex.Status == 401 || ex.Status == 403 {
+		return Verdict{Failed: true, Rule: rule("status=" + strconv.Itoa(ex.Status))}
+	}
+	return Verdict{}
+}
+
+// networkOracle
+// @Description: 在页面规则之外叠加网络判定：JSON/状态码的明确结论优先，其次页面规则，最后是新下发的令牌
+type networkOracle struct {
+	inner Oracle
+}
+
+func (o networkOracle) Evaluate(ctx context.Context, b *Browser) Verdict {
+	ex := b.loginExchange()
+	if v := NetworkVerdict(ex); v.Success || v.Failed {
+		return v
+	}
+	if v := o.inner.Evaluate(ctx, b); v.Success || v.Failed {
+		return v
+	}
+	if ex != nil && ex.Status < 400 && len(ex.Tokens) > 0 {
+		names := make([]string, 0, len(ex.Tokens))
+		for name := range ex.Tokens {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return Verdict{Success: true, Rule: fmt.Sprintf("network:tokens=%s (%s %s)", strings.Join(names, ","), ex.Method, ex.URL)}
+	}
+	return Verdict{}
+}
+
+// ResponseStatus
+// @Description: 登录请求的状态码，未开启网络观察或没有捕获到时返回当前文档的状态码
+// @receiver b
+// @return int
+func (b *Browser) ResponseStatus() int {
+	b.mu.Lock()
+	status := b.lastStatus
+	b.mu.Unlock()
+
+	if ex := b.loginExchange(); ex != nil && ex.Status > 0 {
+		return ex.Status
+	}
+	return status
+}
@@ -0,0 +1,401 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-rod/rod"
+)
+
+// 判定规则类型
+const (
+	IndicatorElement       = "element"        // 元素存在且可见
+	IndicatorElementAbsent = "element_absent" // 元素不存在或不可见
+	IndicatorCookie        = "cookie"         // Cookie 已设置，value 可选为值的正则
+	IndicatorURL           = "url"            // 当前 URL 匹配正则
+	IndicatorText          = "text"           // 页面文本匹配正则
+	IndicatorStatus        = "status"         // 当前文档的 HTTP 状态码，如 200、2xx、301,302
+	IndicatorJS            = "js"             // JS 表达式结果为真
+)
+
+// IndicatorTypes 全部规则类型
+var IndicatorTypes = []string{
+	IndicatorElement,
+	IndicatorElementAbsent,
+	IndicatorCookie,
+	IndicatorURL,
+	IndicatorText,
+	IndicatorStatus,
+	IndicatorJS,
+}
+
+// 多条规则的组合方式
+const (
+	MatchAny = "any"
+	MatchAll = "all"
+)
+
+// Indicator
+// @Description: 登录结果判定规则。设置 all/any 时为规则组，否则按 type 判定 selector；
+// not 对结果取反
+type Indicator struct {
+	Type     string       `yaml:"type" json:"type,omitempty"`
+	Selector string       `yaml:"selector" json:"selector,omitempty"`
+	Value    string       `yaml:"value" json:"value,omitempty"`
+	Name     string       `yaml:"name" json:"name,omitempty"`
+	Not      bool         `yaml:"not" json:"not,omitempty"`
+	All      []*Indicator `yaml:"all" json:"all,omitempty"`
+	Any      []*Indicator `yaml:"any" json:"any,omitempty"`
+}
+
+// String
+// @Description: 规则描述，用于记录命中的规则
+// @receiver i
+// @return string
+func (i *Indicator) String() string {
+	if i.Name != "" {
+		return i.Name
+	}
+
+	var s string
+	switch {
+	case len(i.All) > 0:
+		s = groupString(MatchAll, i.All)
+	case len(i.Any) > 0:
+		s = groupString(MatchAny, i.Any)
+	default:
+		s = i.Type + ":" + i.Selector
+		if i.Value != "" {
+			s += "=" + i.Value
+		}
+	}
+	if i.Not {
+		return "not " + s
+	}
+	return s
+}
+
+func groupString(match string, list []*Indicator) string {
+	parts := make([]string, 0, len(list))
+	for _, item := range list {
+		parts = append(parts, item.String())
+	}
+	return match + "(" + strings.Join(parts, ", ") + ")"
+}
+
+// Check
+// @Description: 检查规则本身是否合法（类型、正则、状态码格式）
+// @receiver i
+// @return error
+func (i *Indicator) Check() error {
+	if len(i.All) > 0 || len(i.Any) > 0 {
+		if i.Type != "" || i.Selector != "" {
+			return fmt.Errorf("rule group cannot also set type or selector")
+		}
+		for _, item := range append(append([]*Indicator{}, i.All...), i.Any...) {
+			if err := item.Check(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if i.Selector == "" {
+		return fmt.Errorf("%s rule requires a selector", i.Type)
+	}
+	switch i.Type {
+	case IndicatorElement, IndicatorElementAbsent, IndicatorJS:
+	case IndicatorURL, IndicatorText:
+		if _, err := regexp.Compile(i.Selector); err != nil {
+			return fmt.Errorf("invalid %s pattern: %w", i.Type, err)
+		}
+	case IndicatorCookie:
+		if i.Value != "" {
+			if _, err := regexp.Compile(i.Value); err != nil {
+				return fmt.Errorf("invalid cookie value pattern: %w", err)
+			}
+		}
+	case IndicatorStatus:
+		if _, err := matchStatus(i.Selector, 0); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown rule type %q, expected one of %s", i.Type, strings.Join(IndicatorTypes, ", "))
+	}
+	return nil
+}
+
+// matchStatus
+// @Description: 状态码是否匹配，支持 200、2xx 以及逗号分隔的多个取值
+// @param pattern
+// @param status
+// @return bool
+// @return error
+func matchStatus(pattern string, status int) (bool, error) {
+	code := strconv.Itoa(status)
+	matched := false
+	for _, part := range strings.Split(pattern, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if len(part) != 3 {
+			return false, fmt.Errorf("invalid status pattern %q", part)
+		}
+		ok := true
+		for k := 0; k < 3; k++ {
+			switch {
+			case part[k] == 'x':
+			case part[k] >= '0' && part[k] <= '9':
+				ok = ok && k < len(code) && code[k] == part[k]
+			default:
+				return false, fmt.Errorf("invalid status pattern %q", part)
+			}
+		}
+		matched = matched || (ok && status > 0)
+	}
+	return matched, nil
+}
+
+// Verdict
+// @Description: 一次判定的结果，Success 与 Failed 都为 false 时表示还没有结论
+type Verdict struct {
+	Success    bool
+	Failed     bool
+	Rule       string
+	Similarity float64 // 设置了失败基线时，与基线的相似度
+}
+
+// Oracle
+// @Description: 登录结果判定接口
+type Oracle interface {
+	Evaluate(ctx context.Context, b *Browser) Verdict
+}
+
+// RuleOracle
+// @Description: 基于 successIndicators / errorIndicators 规则的判定，成功规则优先
+type RuleOracle struct {
+	Success []*Indicator
+	Error   []*Indicator
+	Match   string
+}
+
+// DefaultOracle
+// @Description: 未配置规则时的默认判定：出现用户信息/退出等元素，
+// 或已离开登录路径且没有错误提示时视为成功；出现 alert 提示视为失败
+// @return *RuleOracle
+func DefaultOracle() *RuleOracle {
+	var userElements []*Indicator
+	for _, selector := range []string{".user-info", ".user-profile", ".logout-btn", "#logout", ".welcome-message"} {
+		userElements = append(userElements, &Indicator{Type: IndicatorElement, Selector: selector})
+	}
+
+	leftLoginPage := []*Indicator{
+		{Type: IndicatorURL, Selector: `/login|/signin|/auth`, Not: true},
+	}
+	for _, selector := range []string{".error-message", ".alert-error", ".login-error", ".colorR"} {
+		leftLoginPage = append(leftLoginPage, &Indicator{Type: IndicatorElementAbsent, Selector: selector})
+	}
+
+	return &RuleOracle{
+		Success: append(userElements, &Indicator{All: leftLoginPage}),
+		Error: []*Indicator{
+			{Type: IndicatorElement, Selector: "div[role='alert']"},
+		},
+		Match: MatchAny,
+	}
+}
+
+// NewRuleOracle
+// @Description: 按选择器中配置的规则创建判定，未配置成功规则时使用默认判定
+// @param s
+// @return *RuleOracle
+func NewRuleOracle(s *Selector) *RuleOracle {
+	if s == nil || len(s.SuccessIndicators) == 0 {
+		o := DefaultOracle()
+		if s != nil && len(s.ErrorIndicators) > 0 {
+			o.Error = s.ErrorIndicators
+		}
+		return o
+	}
+	return &RuleOracle{
+		Success: s.SuccessIndicators,
+		Error:   s.ErrorIndicators,
+		Match:   s.IndicatorMatch,
+	}
+}
+
+// Evaluate
+// @Description: 先检查成功规则，再检查错误规则
+// @receiver o
+// @param ctx
+// @param b
+// @return Verdict
+func (o *RuleOracle) Evaluate(ctx context.Context, b *Browser) Verdict {
+	if len(o.Success) > 0 {
+		if o.Match == MatchAll {
+			if b.matchAll(ctx, o.Success) {
+				return Verdict{Success: true, Rule: groupString(MatchAll, o.Success)}
+			}
+		} else if rule := b.firstMatch(ctx, o.Success); rule != nil {
+			return Verdict{Success: true, Rule: rule.String()}
+		}
+	}
+
+	if rule := b.firstMatch(ctx, o.Error); rule != nil {
+		return Verdict{Failed: true, Rule: rule.String()}
+	}
+	return Verdict{}
+}
+
+// firstMatch
+// @Description: 第一条命中的规则
+// @receiver b
+// @param ctx
+// @param list
+// @return *Indicator
+func (b *Browser) firstMatch(ctx context.Context, list []*Indicator) *Indicator {
+	for _, item := range list {
+		if b.matchIndicator(ctx, item) {
+			return item
+		}
+	}
+	return nil
+}
+
+// matchAll
+// @Description: 全部规则是否命中
+// @receiver b
+// @param ctx
+// @param list
+// @return bool
+func (b *Browser) matchAll(ctx context.Context, list []*Indicator) bool {
+	for _, item := range list {
+		if !b.matchIndicator(ctx, item) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchIndicator
+// @Description: 在当前页面上判定单条规则，页面读取失败视为未命中
+// @receiver b
+// @param ctx
+// @param i
+// @return bool
+func (b *Browser) matchIndicator(ctx context.Context, i *Indicator) bool {
+	var matched bool
+	switch {
+	case len(i.All) > 0:
+		matched = b.matchAll(ctx, i.All)
+	case len(i.Any) > 0:
+		matched = b.firstMatch(ctx, i.Any) != nil
+	default:
+		matched = b.matchRule(ctx, i)
+	}
+	return matched != i.Not
+}
+
+func (b *Browser) matchRule(ctx context.Context, i *Indicator) bool {
+	page := b.query(ctx)
+
+	switch i.Type {
+	case IndicatorElement, IndicatorElementAbsent:
+		visible := false
+		if el, err := b.resolve(ctx, i.Selector); err == nil && el != nil {
+			visible, _ = el.Visible()
+		}
+		return visible == (i.Type == IndicatorElement)
+	case IndicatorCookie:
+		cookies, err := page.Cookies(nil)
+		if err != nil {
+			return false
+		}
+		for _, cookie := range cookies {
+			if cookie.Name != i.Selector {
+				continue
+			}
+			if i.Value == "" {
+				return true
+			}
+			if ok, _ := regexp.MatchString(i.Value, cookie.Value); ok {
+				return true
+			}
+		}
+		return false
+	case IndicatorURL:
+		ok, _ := regexp.MatchString(i.Selector, b.currentURL())
+		return ok
+	case IndicatorText:
+		res, err := page.Eval(`() => document.body ? document.body.innerText : ''`)
+		if err != nil {
+			return false
+		}
+		ok, _ := regexp.MatchString(i.Selector, res.Value.Str())
+		return ok
+	case IndicatorStatus:
+		b.mu.Lock()
+		status := b.lastStatus
+		b.mu.Unlock()
+		ok, _ := matchStatus(i.Selector, status)
+		return ok
+	case IndicatorJS:
+		res, err := page.Evaluate(rod.Eval(`(expr) => Boolean((0, eval)(expr))`, i.Selector))
+		return err == nil && res.Value.Bool()
+	}
+	return false
+}
+
+// SetOracle
+// @Description: 设置自定义的登录结果判定，未设置时按选择器中的规则判定
+// @receiver b
+// @param o
+func (b *Browser) SetOracle(o Oracle) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.oracle = o
+}
+
+// oracleFor
+// @Description: 本次登录使用的判定
+// @receiver b
+// @param s
+// @return Oracle
+func (b *Browser) oracleFor(s *Selector) Oracle {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var o Oracle
+	switch {
+	case b.oracle != nil:
+		o = b.oracle
+	case b.baseline == nil || (s != nil && len(s.SuccessIndicators) > 0):
+		o = NewRuleOracle(s)
+	}
+
+	// 有失败基线时不再使用默认判定，只保留配置的错误规则
+	if b.baseline != nil {
+		var inner Oracle = o
+		if o == nil && s != nil && len(s.ErrorIndicators) > 0 {
+			inner = &RuleOracle{Error: s.ErrorIndicators}
+		}
+		o = baselineOracle{inner: inner, baseline: b.baseline, threshold: b.baselineThreshold}
+	}
+	if b.observer != nil {
+		o = networkOracle{inner: o}
+	}
+	return o
+}
+
+// LastVerdict
+// @Description: 最近一次登录的判定结果
+// @receiver b
+// @return Verdict
+func (b *Browser) LastVerdict() Verdict {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.verdict
+}
@@ -0,0 +1,158 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-rod/rod"
+)
+
+// SelectorKind 选择器类型
+type SelectorKind string
+
+const (
+	KindCSS   SelectorKind = "css"
+	KindXPath SelectorKind = "xpath"
+	KindText  SelectorKind = "text"
+	KindRole  SelectorKind = "role"
+)
+
+// Query
+// @Description: 解析后的选择器。选择器字符串可以带显式前缀（css:、xpath:、text:、role:），
+// 不带前缀时以 / 或 ( 开头的视为 XPath，其余视为 CSS。
+// role 支持可访问名称过滤，如 role:button[name="登录"]
+type Query struct {
+	Kind  SelectorKind
+	Value string
+	Name  string
+}
+
+var rolePattern = regexp.MustCompile(`^([\w-]+)(?:\s*\[\s*name\s*=\s*["']?(.*?)["']?\s*\])?$`)
+
+// ParseSelector
+// @Description: 解析选择器字符串
+// @param raw
+// @return Query
+func ParseSelector(raw string) Query {
+	raw = strings.TrimSpace(raw)
+
+	for _, kind := range []SelectorKind{KindCSS, KindXPath, KindText, KindRole} {
+		prefix := string(kind) + ":"
+		if len(raw) > len(prefix) && strings.EqualFold(raw[:len(prefix)], prefix) {
+			q := Query{Kind: kind, Value: strings.TrimSpace(raw[len(prefix):])}
+			if kind == KindRole {
+				if m := rolePattern.FindStringSubmatch(q.Value); m != nil {
+					q.Value, q.Name = strings.ToLower(m[1]), m[2]
+				}
+			}
+			return q
+		}
+	}
+
+	if strings.HasPrefix(raw, "/") || strings.HasPrefix(raw, "(") || strings.HasPrefix(raw, "./") {
+		return Query{Kind: KindXPath, Value: raw}
+	}
+	return Query{Kind: KindCSS, Value: raw}
+}
+
+// String
+// @Description: 带前缀的规范写法
+// @receiver q
+// @return string
+func (q Query) String() string {
+	if q.Kind == KindRole && q.Name != "" {
+		return fmt.Sprintf("role:%s[name=%q]", q.Value, q.Name)
+	}
+	return string(q.Kind) + ":" + q.Value
+}
+
+// matchJS 按文本或角色在整个页面中查找全部匹配元素，可见的排在前面
+const matchJS = `(kind, value, name) => {
+	const norm = s => (s || '').replace(/\s+/g, ' ').trim();
+	const label = el => norm(el.innerText || el.value || el.getAttribute('aria-label') ||
+		el.getAttribute('title') || el.getAttribute('placeholder') || el.getAttribute('alt'));
+	const visible = el => {
+		const rect = el.getBoundingClientRect();
+		const style = getComputedStyle(el);
+		return rect.width > 0 && rect.height > 0 && style.visibility !== 'hidden' && style.display !== 'none';
+	};
+
+	let candidates;
+	if (kind === 'text') {
+		candidates = Array.from(document.querySelectorAll('body *')).filter(el => label(el).includes(value));
+		// 只保留最内层的元素，外层容器的文本同样包含目标文本
+		candidates = candidates.filter(el => !candidates.some(o => o !== el && el.contains(o)));
+		// 文本最短的通常最贴近
+		candidates.sort((a, b) => label(a).length - label(b).length);
+	} else {
+		const roles = {
+			button: 'button, input[type=submit], input[type=button], input[type=image], [role=button]',
+			textbox: 'input:not([type]), input[type=text], input[type=email], input[type=tel], input[type=number], textarea, [role=textbox]',
+			checkbox: 'input[type=checkbox], [role=checkbox]',
+			link: 'a[href], [role=link]',
+			img: 'img, [role=img]',
+		};
+		candidates = Array.from(document.querySelectorAll(roles[value] || '[role="' + CSS.escape(value) + '"]'));
+		if (name) {
+			candidates = candidates.filter(el => label(el).includes(name));
+		}
+	}
+	return candidates.filter(visible).concat(candidates.filter(el => !visible(el)));
+}`
+
+// resolve
+// @Description: 按选择器类型查找元素，只查找一次，不等待
+// @receiver b
+// @param ctx
+// @param raw 选择器字符串
+// @return *rod.Element
+// @return error
+func (b *Browser) resolve(ctx context.Context, raw string) (*rod.Element, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, fmt.Errorf("empty selector")
+	}
+
+	page := b.query(ctx)
+	q := ParseSelector(raw)
+	switch q.Kind {
+	case KindXPath:
+		return page.ElementX(q.Value)
+	case KindText, KindRole:
+		els, err := page.ElementsByJS(rod.Eval(matchJS, string(q.Kind), q.Value, q.Name))
+		if err != nil {
+			return nil, err
+		}
+		if els.Empty() {
+			return nil, &rod.ElementNotFoundError{}
+		}
+		return els.First(), nil
+	default:
+		return page.Element(q.Value)
+	}
+}
+
+// resolveAll
+// @Description: 按选择器类型查找全部匹配元素，只查找一次，不等待
+// @receiver b
+// @param ctx
+// @param raw 选择器字符串
+// @return rod.Elements
+// @return error
+func (b *Browser) resolveAll(ctx context.Context, raw string) (rod.Elements, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, fmt.Errorf("empty selector")
+	}
+
+	page := b.query(ctx)
+	q := ParseSelector(raw)
+	switch q.Kind {
+	case KindXPath:
+		return page.ElementsX(q.Value)
+	case KindText, KindRole:
+		return page.ElementsByJS(rod.Eval(matchJS, string(q.Kind), q.Value, q.Name))
+	default:
+		return page.Elements(q.Value)
+	}
+}
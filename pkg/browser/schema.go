@@ -0,0 +1,334 @@
+package browser
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// SelectorSchema 选择器文件的 JSON Schema
+//
+//go:embed schema/selector.schema.json
+var SelectorSchema []byte
+
+// schemaNode
+// @Description: 校验用到的 JSON Schema 关键字
+type schemaNode struct {
+	Ref                  string                 `json:"$ref"`
+	Type                 string                 `json:"type"`
+	Enum                 []string               `json:"enum"`
+	Required             []string               `json:"required"`
+	Properties           map[string]*schemaNode `json:"properties"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	Items                *schemaNode            `json:"items"`
+	MinItems             int                    `json:"minItems"`
+	MinLength            int                    `json:"minLength"`
+	OneOf                []*schemaNode          `json:"oneOf"`
+	Deprecated           bool                   `json:"deprecated"`
+	Defs                 map[string]*schemaNode `json:"$defs"`
+}
+
+// selectorSchema 解析后的 SelectorSchema
+var selectorSchema = func() *schemaNode {
+	var root schemaNode
+	if err := json.Unmarshal(SelectorSchema, &root); err != nil {
+		panic(fmt.Sprintf("invalid built-in selector schema: %v", err))
+	}
+	return &root
+}()
+
+// schemaChecks Schema 无法表达的检查（正则、状态码格式），按 $defs 名称在 Schema 校验通过后执行
+var schemaChecks = map[string]func(node *yaml.Node) error{
+	"indicator": func(node *yaml.Node) error {
+		var rule Indicator
+		if err := node.Decode(&rule); err != nil {
+			return err
+		}
+		// 规则组的子规则会单独校验
+		if len(rule.All) > 0 || len(rule.Any) > 0 {
+			return nil
+		}
+		return rule.Check()
+	},
+}
+
+// SchemaError
+// @Description: 选择器文件校验问题
+type SchemaError struct {
+	Line    int
+	Field   string
+	Message string
+}
+
+func (e SchemaError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return fmt.Sprintf("line %d: %s: %s", e.Line, e.Field, e.Message)
+}
+
+// SchemaErrors 多个校验错误
+type SchemaErrors []SchemaError
+
+func (errs SchemaErrors) Error() string {
+	var lines []string
+	for _, e := range errs {
+		lines = append(lines, e.Error())
+	}
+	return strings.Join(lines, "; ")
+}
+
+// ValidateSelectorData
+// @Description: 按 SelectorSchema 校验选择器文件内容（YAML 或 JSON），未知字段同样是错误，返回带行号的问题列表
+// @param data
+// @return []SchemaError
+func ValidateSelectorData(data []byte) []SchemaError {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return []SchemaError{{Line: yamlErrorLine(err), Message: err.Error()}}
+	}
+	if len(doc.Content) == 0 {
+		return []SchemaError{{Line: 1, Message: "selector file is empty"}}
+	}
+	return selectorSchema.validate(doc.Content[0], "")
+}
+
+// resolve
+// @Description: 解析 $ref，只支持本文件中的 #/$defs/ 引用
+// @receiver s
+// @param ref
+// @return *schemaNode
+// @return string $defs 中的名称
+func (s *schemaNode) resolve(ref string) (*schemaNode, string) {
+	name := strings.TrimPrefix(ref, "#/$defs/")
+	def, ok := selectorSchema.Defs[name]
+	if !ok {
+		panic(fmt.Sprintf("selector schema: unresolved $ref %q", ref))
+	}
+	return def, name
+}
+
+// kind 该 Schema 期望的 YAML 节点类型，未声明时为 0
+func (s *schemaNode) kind() yaml.Kind {
+	if s.Ref != "" {
+		def, _ := s.resolve(s.Ref)
+		return def.kind()
+	}
+	switch s.Type {
+	case "object":
+		return yaml.MappingNode
+	case "array":
+		return yaml.SequenceNode
+	case "string", "boolean", "integer":
+		return yaml.ScalarNode
+	}
+	return 0
+}
+
+// validate
+// @Description: 按 Schema 校验 YAML 节点
+// @receiver s
+// @param node
+// @param field 所属的顶层字段，用于报告
+// @return []SchemaError
+func (s *schemaNode) validate(node *yaml.Node, field string) []SchemaError {
+	fail := func(n *yaml.Node, format string, args ...interface{}) []SchemaError {
+		return []SchemaError{{Line: n.Line, Field: field, Message: fmt.Sprintf(format, args...)}}
+	}
+
+	var errs []SchemaError
+	if s.Ref != "" {
+		def, name := s.resolve(s.Ref)
+		if errs = def.validate(node, field); len(errs) == 0 && schemaChecks[name] != nil {
+			if err := schemaChecks[name](node); err != nil {
+				errs = fail(node, "%v", err)
+			}
+		}
+	}
+
+	switch s.Type {
+	case "object":
+		if node.Kind != yaml.MappingNode {
+			return append(errs, fail(node, "must be a mapping")...)
+		}
+	case "array":
+		if node.Kind != yaml.SequenceNode {
+			return append(errs, fail(node, "must be a list")...)
+		}
+	case "string":
+		// 空值按空字符串处理，由 minLength 判断
+		if node.Kind != yaml.ScalarNode || (node.Tag != "!!str" && node.Tag != "!!null") {
+			return append(errs, fail(node, "must be a string")...)
+		}
+	case "boolean":
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			return append(errs, fail(node, "must be a boolean")...)
+		}
+	case "integer":
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+			return append(errs, fail(node, "must be an integer")...)
+		}
+	}
+
+	if len(s.Enum) > 0 {
+		found := node.Kind == yaml.ScalarNode
+		if found {
+			found = false
+			for _, v := range s.Enum {
+				found = found || v == node.Value
+			}
+		}
+		if !found {
+			errs = append(errs, fail(node, "must be one of %s", strings.Join(s.Enum, ", "))...)
+		}
+	}
+	if s.MinLength > 0 && node.Kind == yaml.ScalarNode && len(strings.TrimSpace(node.Value)) < s.MinLength {
+		errs = append(errs, fail(node, "must not be empty")...)
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		errs = append(errs, s.validateMapping(node, field)...)
+	case yaml.SequenceNode:
+		if len(node.Content) < s.MinItems {
+			errs = append(errs, fail(node, "must have at least %d item(s)", s.MinItems)...)
+		}
+		if s.Items != nil {
+			for _, item := range node.Content {
+				errs = append(errs, s.Items.validate(item, field)...)
+			}
+		}
+	}
+
+	if len(s.OneOf) > 0 {
+		errs = append(errs, s.validateOneOf(node, field)...)
+	}
+	return errs
+}
+
+// validateMapping
+// @Description: 校验重复键、必填键和未知键，并逐个校验已声明的属性
+// @receiver s
+// @param node
+// @param field
+// @return []SchemaError
+func (s *schemaNode) validateMapping(node *yaml.Node, field string) []SchemaError {
+	var errs []SchemaError
+	seen := make(map[string]bool)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		name := field
+		if name == "" {
+			name = key.Value
+		}
+
+		if seen[key.Value] {
+			errs = append(errs, SchemaError{Line: key.Line, Field: name, Message: fmt.Sprintf("duplicate key %q", key.Value)})
+			continue
+		}
+		seen[key.Value] = true
+
+		property, ok := s.Properties[key.Value]
+		if !ok {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				errs = append(errs, SchemaError{Line: key.Line, Field: name, Message: fmt.Sprintf("unknown field %q", key.Value)})
+			}
+			continue
+		}
+		if property.Deprecated {
+			log.WithFields(log.Fields{
+				"line":  key.Line,
+				"field": name,
+			}).Warn("Deprecated selector field is ignored")
+		}
+		errs = append(errs, property.validate(value, name)...)
+	}
+
+	// oneOf 中的 required 由 validateOneOf 报告
+	for _, name := range s.Required {
+		if !seen[name] {
+			field := field
+			if field == "" {
+				field = name
+			}
+			errs = append(errs, SchemaError{Line: node.Line, Field: field, Message: fmt.Sprintf("required field %q is missing", name)})
+		}
+	}
+	return errs
+}
+
+// validateOneOf
+// @Description: 节点必须恰好符合一个备选；都不符合时报告与节点类型一致的备选的问题
+// @receiver s
+// @param node
+// @param field
+// @return []SchemaError
+func (s *schemaNode) validateOneOf(node *yaml.Node, field string) []SchemaError {
+	var matched int
+	var closest []SchemaError
+	var forms []string
+	for _, option := range s.OneOf {
+		errs := option.validate(node, field)
+		if len(errs) == 0 {
+			matched++
+			continue
+		}
+		if option.kind() == node.Kind && closest == nil {
+			closest = errs
+		}
+		if len(option.Required) > 0 {
+			forms = append(forms, strings.Join(option.Required, "+"))
+		}
+	}
+
+	switch {
+	case matched == 1:
+		return nil
+	case matched > 1:
+		return []SchemaError{{Line: node.Line, Field: field, Message: "matches more than one allowed form"}}
+	case closest != nil:
+		return closest
+	case len(forms) > 0:
+		return []SchemaError{{Line: node.Line, Field: field, Message: "must set one of " + strings.Join(forms, ", ")}}
+	}
+	return []SchemaError{{Line: node.Line, Field: field, Message: "must be a string or a list of strings"}}
+}
+
+// yamlErrorLine
+// @Description: 从 yaml 语法错误中提取行号
+// @param err
+// @return int
+func yamlErrorLine(err error) int {
+	var line int
+	if _, scanErr := fmt.Sscanf(strings.TrimPrefix(err.Error(), "yaml: "), "line %d:", &line); scanErr == nil {
+		return line
+	}
+	return 0
+}
+
+// LoadSelectorFile
+// @Description: 严格加载选择器文件，存在任何问题（包括未知字段）时返回 SchemaErrors
+// @param path
+// @return *Selector
+// @return error
+func LoadSelectorFile(path string) (*Selector, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read selector file: %w", err)
+	}
+
+	if errs := ValidateSelectorData(data); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid selector file %s: %w", path, SchemaErrors(errs))
+	}
+
+	s := &Selector{}
+	if err = yaml.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse selector file %s: %w", path, err)
+	}
+	return s, nil
+}
@@ -0,0 +1,234 @@
+package browser
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// FormFeatures
+// @Description: 参与评分的表单特征，由 formFeaturesJS 在页面中收集
+type FormFeatures struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Class  string `json:"className"`
+	Action string `json:"action"`
+	Method string `json:"method"`
+	Role   string `json:"role"`
+
+	Types   []string `json:"types"`   // 可见输入框的类型
+	Buttons []string `json:"buttons"` // 可见按钮的文本
+	Labels  []string `json:"labels"`  // 输入框的 placeholder、name、id、aria-label 与 label 文本
+	Text    string   `json:"text"`    // 表单内的文本（截断）
+
+	X              float64 `json:"x"`
+	Y              float64 `json:"y"`
+	Width          float64 `json:"width"`
+	Height         float64 `json:"height"`
+	ViewportWidth  float64 `json:"viewportWidth"`
+	ViewportHeight float64 `json:"viewportHeight"`
+}
+
+// formFeaturesJS 在表单元素或虚拟容器上执行，收集 FormFeatures
+const formFeaturesJS = `function () {
+	const norm = s => (s || '').replace(/\s+/g, ' ').trim();
+	const visible = el => {
+		const rect = el.getBoundingClientRect();
+		const style = getComputedStyle(el);
+		return rect.width > 0 && rect.height > 0 && style.visibility !== 'hidden' && style.display !== 'none';
+	};
+	const inputs = Array.from(this.querySelectorAll('input, select, textarea'))
+		.filter(el => el.type !== 'hidden' && visible(el));
+	const buttons = Array.from(this.querySelectorAll("button, input[type='submit'], input[type='button'], input[type='image'], [role='button']"))
+		.filter(visible);
+	const labels = [];
+	for (const el of inputs) {
+		labels.push(el.placeholder, el.name, el.id, el.getAttribute('aria-label'));
+		for (const label of Array.from(el.labels || [])) labels.push(label.innerText);
+	}
+	const rect = this.getBoundingClientRect();
+	return {
+		id: this.id || '',
+		name: this.getAttribute('name') || '',
+		className: typeof this.className === 'string' ? this.className : '',
+		action: this.getAttribute('action') || '',
+		method: (this.getAttribute('method') || (this.tagName === 'FORM' ? 'get' : '')).toLowerCase(),
+		role: this.getAttribute('role') || '',
+		types: inputs.map(el => (el.type || el.tagName).toLowerCase()),
+		buttons: buttons.map(el => norm(el.innerText || el.value || el.getAttribute('aria-label') || el.title)).filter(Boolean),
+		labels: labels.map(norm).filter(Boolean),
+		text: norm(this.innerText).slice(0, 500),
+		x: rect.x, y: rect.y, width: rect.width, height: rect.height,
+		viewportWidth: window.innerWidth, viewportHeight: window.innerHeight,
+	};
+}`
+
+// 评分用的关键词，按语言分组，匹配时不区分大小写
+var (
+	loginKeywords = []string{
+		// zh
+		"登录", "登 录", "登陆", "登入",
+		// en
+		"log in", "login", "sign in", "signin",
+		// ja
+		"ログイン", "サインイン",
+	}
+	registerKeywords = []string{
+		// zh
+		"注册", "确认密码", "重复密码", "再次输入",
+		// en
+		"register", "sign up", "signup", "create account", "confirm password",
+		// ja
+		"新規登録", "会員登録", "パスワード確認",
+	}
+	searchKeywords = []string{
+		// zh
+		"搜索", "查询",
+		// en
+		"search",
+		// ja
+		"検索",
+	}
+)
+
+// 评分权重
+const (
+	scoreUser       = 20  // 找到用户名输入框，另加命中模式的权重
+	scorePass       = 30  // 找到密码输入框，另加命中模式的权重
+	scoreButton     = 10  // 找到登录按钮，另加命中模式的权重
+	scoreCaptcha    = 5   // 找到验证码输入框
+	scoreCaptchaImg = 3   // 找到验证码图片
+	scoreRemember   = 2   // 找到记住我复选框
+	scoreOnePass    = 10  // 恰好一个密码框
+	scoreLoginText  = 15  // 按钮文本为登录
+	scoreLoginHint  = 5   // 表单内的其他文本提到登录
+	scoreCentered   = 5   // 位于首屏水平居中位置
+	penaltyPasses   = -30 // 多个密码框，通常是注册或修改密码
+	penaltyRegister = -30 // 注册表单
+	penaltySearch   = -40 // 搜索表单
+	penaltyInputs   = -5  // 超过 maxTextInputs 的每个文本输入框
+	penaltyHidden   = -50 // 表单不可见
+	penaltySmall    = -10 // 表单过小
+	penaltyBelow    = -5  // 位于两屏以下
+
+	maxTextInputs = 3
+	fullScore     = 100 // 置信度为 1 的分数
+)
+
+// LowConfidence 置信度低于该值时提示表单可能不是登录表单
+const LowConfidence = 0.5
+
+// fieldScores 各字段找到时的基础分
+var fieldScores = map[string]int{
+	FieldUserInput:     scoreUser,
+	FieldPasswordInput: scorePass,
+	FieldLoginBtn:      scoreButton,
+	FieldCaptchaInput:  scoreCaptcha,
+	FieldCaptchaImg:    scoreCaptchaImg,
+	FieldRememberMe:    scoreRemember,
+}
+
+// FormScore
+// @Description: 表单的评分结果
+type FormScore struct {
+	Score      int      `json:"score"`
+	Confidence float64  `json:"confidence"` // 0~1，Score 按 fullScore 归一化
+	Reasons    []string `json:"reasons"`    // 各项加减分，如 "passwordInput+40"
+}
+
+func (s *FormScore) add(points int, reason string) {
+	s.Score += points
+	s.Reasons = append(s.Reasons, fmt.Sprintf("%s%+d", reason, points))
+}
+
+// ScoreForm
+// @Description: 按找到的字段、输入框类型、按钮与标签文本、尺寸与位置为表单评分，注册和搜索表单扣分
+// @param f 表单特征
+// @param fields 找到的字段及命中模式的权重
+// @return FormScore
+func ScoreForm(f *FormFeatures, fields map[string]int) FormScore {
+	var s FormScore
+
+	for _, field := range SelectorFields {
+		if weight, ok := fields[field]; ok {
+			s.add(fieldScores[field]+weight, field)
+		}
+	}
+
+	passwords, textInputs := 0, 0
+	for _, t := range f.Types {
+		switch t {
+		case "password":
+			passwords++
+			textInputs++
+		case "text", "email", "tel", "number":
+			textInputs++
+		}
+	}
+	switch {
+	case passwords == 1:
+		s.add(scoreOnePass, "onePassword")
+	case passwords > 1:
+		s.add(penaltyPasses, "multiplePasswords")
+	}
+	if textInputs > maxTextInputs {
+		s.add(penaltyInputs*(textInputs-maxTextInputs), "manyInputs")
+	}
+
+	buttons := strings.ToLower(strings.Join(f.Buttons, " | "))
+	hints := strings.ToLower(strings.Join(append(append([]string{}, f.Labels...), f.Text), " | "))
+	attrs := strings.ToLower(strings.Join([]string{f.ID, f.Name, f.Class, f.Action}, " "))
+
+	loginButton := firstKeyword(buttons, loginKeywords)
+	if loginButton != "" {
+		s.add(scoreLoginText, "button:"+loginButton)
+	} else if k := firstKeyword(hints+" "+attrs, loginKeywords); k != "" {
+		s.add(scoreLoginHint, "text:"+k)
+	}
+
+	// 登录表单中常有注册链接或按钮，只看属性和输入框标签；没有登录按钮时才看按钮文本
+	register := attrs + " " + strings.ToLower(strings.Join(f.Labels, " | "))
+	if loginButton == "" {
+		register += " " + buttons
+	}
+	if k := firstKeyword(register, registerKeywords); k != "" {
+		s.add(penaltyRegister, "register:"+k)
+	}
+
+	if passwords == 0 {
+		search := strings.EqualFold(f.Role, "search")
+		for _, t := range f.Types {
+			search = search || t == "search"
+		}
+		if search || firstKeyword(buttons+" "+attrs, searchKeywords) != "" {
+			s.add(penaltySearch, "search")
+		}
+	}
+
+	switch {
+	case f.Width <= 0 || f.Height <= 0:
+		s.add(penaltyHidden, "hidden")
+	case f.Width < 150 || f.Height < 60:
+		s.add(penaltySmall, "small")
+	}
+	if f.ViewportWidth > 0 && f.ViewportHeight > 0 && f.Width > 0 {
+		centerX := f.X + f.Width/2
+		if centerX >= f.ViewportWidth*0.2 && centerX <= f.ViewportWidth*0.8 && f.Y < f.ViewportHeight {
+			s.add(scoreCentered, "centered")
+		} else if f.Y > f.ViewportHeight*2 {
+			s.add(penaltyBelow, "farBelow")
+		}
+	}
+
+	s.Confidence = math.Round(math.Max(0, math.Min(1, float64(s.Score)/fullScore))*100) / 100
+	return s
+}
+
+func firstKeyword(text string, keywords []string) string {
+	for _, k := range keywords {
+		if strings.Contains(text, strings.ToLower(k)) {
+			return k
+		}
+	}
+	return ""
+}
@@ -1,6 +1,7 @@
 package browser
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"time"
@@ -10,183 +11,128 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// FormDesc
+// @Description: 一个候选表单及其评分
 type FormDesc struct {
-	Form      *rod.Element
-	Score     int
-	HasLogin  bool
-	HasPass   bool
-	HasSubmit bool
-	Position  proto.Point
+	Form *rod.Element `json:"-"`
+	FormScore
+
+	Index     int         `json:"index"`             // 在页面中的顺序（从 0 开始，虚拟容器排在表单之后），用于按序号指定表单
+	Complete  bool        `json:"complete"`          // 是否找到了用户名、密码和登录按钮，只有完整的表单可以被选中
+	Virtual   bool        `json:"virtual,omitempty"` // 没有完整表单时由密码框及附近的输入框和按钮组成的虚拟容器
+	HasLogin  bool        `json:"hasLogin"`
+	HasPass   bool        `json:"hasPass"`
+	HasSubmit bool        `json:"hasSubmit"`
+	Position  proto.Point `json:"position"` // 表单中心点
+	ID        string      `json:"id,omitempty"`
+	Action    string      `json:"action,omitempty"`
+	Method    string      `json:"method,omitempty"`
 	selector  *Selector
 }
 
+// Selector
+// @Description: 登录表单各字段的选择器。选择器文件中每个字段既可以是单个字符串，
+// 也可以是按顺序尝试的候选列表，此时字符串字段保存第一个候选，完整列表保存在 Fallbacks 中
 type Selector struct {
-	UserInput     string `yaml:"userInput" json:"userInput"`
-	PasswordInput string `yaml:"passwordInput" json:"passwordInput"`
-	LoginBtn      string `yaml:"loginBtn" json:"loginBtn"`
-	RememberMe    string `yaml:"rememberMe" json:"rememberMe"`
-	CaptchaInput  string `yaml:"captchaInput" json:"captchaInput"`
-	CaptchaImg    string `yaml:"captchaImg" json:"captchaImg"`
-	form          *rod.Element
+	UserInput     string              `yaml:"userInput" json:"userInput"`
+	PasswordInput string              `yaml:"passwordInput" json:"passwordInput"`
+	LoginBtn      string              `yaml:"loginBtn" json:"loginBtn"`
+	RememberMe    string              `yaml:"rememberMe" json:"rememberMe"`
+	CaptchaInput  string              `yaml:"captchaInput" json:"captchaInput"`
+	CaptchaImg    string              `yaml:"captchaImg" json:"captchaImg"`
+	Fallbacks     map[string][]string `yaml:"-" json:"-"`
+
+	// 登录结果判定规则，未配置成功规则时使用 DefaultOracle
+	SuccessIndicators []*Indicator `yaml:"successIndicators" json:"successIndicators,omitempty"`
+	ErrorIndicators   []*Indicator `yaml:"errorIndicators" json:"errorIndicators,omitempty"`
+	IndicatorMatch    string       `yaml:"indicatorMatch" json:"indicatorMatch,omitempty"`
+
+	form    *rod.Element
+	weights map[string]int // 探测时各字段命中模式的权重
 }
 
-var (
-
-	// Common selectors for form elements
-	userInputSelectors = []string{
-		"input[id='uid']",
-		"input[id='usernameIpt']",
-		"input[id='account']",
-		"input[id='username']",
-		"input[id*='user']",
-		"input[id='loginid']",
-		"input[name*='user']",
-		"input[class*='user']",
-
-		"input[placeholder*='账号']",
-		"input[placeholder*='用户']",
-		"input[placeholder*='工号']",
-		"input[placeholder*='邮箱']",
-
-		"input[name='user[login]']",
-		"input[name='username']",
-		"input[name='uid']",
-		"input[name='account']",
-	}
-
-	passInputSelectors = []string{
-		"input[type='password']",
-		"input[placeholder='密码']",
-		"input[placeholder*='密码']",
-		"input[name='user[password]']",
-		"input[name='pwd']",
-		"input[id='pwd']",
-		"input[id*='pass']",
-		"input[name*='pass']",
-		"input[class*='pass']",
-	}
-
-	loginBtnSelectors = []string{
-		//"button[name='submit']",
-		"button[type='submit']",
-		"button[type='button']",
-		//"button[id*='login-btn']",
-		//"button[class*='login_button']",
-		//"button[class*='btn-login']",
-		//"button[class*='submit-btn']",
-		//"button[class*='el-button']",
-		//"button[value*='登录']",
-		//"button[id*='commit']",
-		//
-		//"input[value='Login']",
-		//"input[value*='Sign in']",
-		//"input[id*='login-btn']",
-		//"input[class*='login_button']",
-		//"input[class*='btn-login']",
-		//"input[name='commit']",
-		//"input[type='submit']",
-		//
-		//"div[class*='login_button']",
-		//"div[class*='btn-login']",
-		//
-		//"#login-btn",
-		//"#loginBtn",
-		//".btn-login",
-		//".login-btn",
-		//".btn-primary",
-	}
-
-	checkBoxSelectors = []string{
-		"input[type='checkbox']",
+// found
+// @Description: 记录探测时字段命中的模式
+// @receiver s
+// @param field
+// @param p
+func (s *Selector) found(field string, p Pattern) {
+	if s.weights == nil {
+		s.weights = make(map[string]int)
 	}
+	s.weights[field] = p.Weight
+}
 
-	captchaInputSelectors = []string{
-		"input[placeholder*='验证码']",
-		"input[placeholder*='verification']",
-		"input[placeholder*='Verification']",
+// scoreLoginForm
+// @Description: 探测表单内的字段并为表单评分，字段不全时也返回评分，Complete 为 false
+// @receiver b
+// @param ctx
+// @param form
+// @return *FormDesc
+// @return error 只在页面无法访问或上下文结束时返回
+func (b *Browser) scoreLoginForm(ctx context.Context, form *rod.Element) (*FormDesc, error) {
+	res, err := form.Context(ctx).Eval(formFeaturesJS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect form features: %w", err)
 	}
-
-	captchaImageSelectors = []string{
-		"img",
-		//"input[id='checkCode']",
-		//"img[id*='captcha']",
-		//"img[id*='Captcha']",
-		//"img[alt*='验证码']",
-		//"img[alt*='captcha']",
-		//"img[src*='captcha']",
-		//"img[src*='verify']",
-		//"img[class*='captcha']",
-		//"img[id*='captcha']",
-		//"img[title*='验证码']",
-		//"img[title*='captcha']",
-
-		".captcha-img",
-		".verify-img",
-
-		// ElementUI
-		".el-image img[src*='captcha']",
-		".el-image[alt*='验证码']",
-		".el-image[alt*='captcha']",
+	var f FormFeatures
+	if err = res.Value.Unmarshal(&f); err != nil {
+		return nil, fmt.Errorf("failed to collect form features: %w", err)
 	}
-)
-
-func (b *Browser) scoreLoginForm(form *rod.Element) (*FormDesc, error) {
-	logger := log.WithField("action", "socre_login_form")
-	_ = logger
-
-	formDesc := &FormDesc{Form: form}
 
-	_selector, err := b.findFormElements(form, true)
-	if err != nil {
+	_selector, err := b.findFormElements(ctx, form, true)
+	if _selector == nil {
 		return nil, err
 	}
 
-	formDesc.selector = _selector
-
-	if _selector.UserInput != "" {
-		formDesc.Score++
-	}
-	if _selector.PasswordInput != "" {
-		formDesc.Score++
-	}
-	if _selector.LoginBtn != "" {
-		formDesc.Score++
+	formDesc := &FormDesc{
+		Form:      form,
+		FormScore: ScoreForm(&f, _selector.weights),
+		Complete:  err == nil,
+		HasLogin:  _selector.UserInput != "",
+		HasPass:   _selector.PasswordInput != "",
+		HasSubmit: _selector.LoginBtn != "",
+		Position:  proto.Point{X: f.X + f.Width/2, Y: f.Y + f.Height/2},
+		ID:        f.ID,
+		Action:    f.Action,
+		Method:    f.Method,
 	}
-	if _selector.CaptchaInput != "" {
-		formDesc.Score++
+	if formDesc.Complete {
+		formDesc.selector = _selector
 	}
-	if _selector.CaptchaImg != "" {
-		formDesc.Score++
-	}
-
 	return formDesc, nil
 }
 
 // findFormElements
-// @Description: 匹配表单内元素
+// @Description: 匹配表单内元素，字段不全时同时返回已找到的部分和错误
 // @receiver b
 // @param form
 // @return *Selector
 // @return error
-func (b *Browser) findFormElements(form *rod.Element, enhance bool) (*Selector, error) {
+func (b *Browser) findFormElements(ctx context.Context, form *rod.Element, enhance bool) (*Selector, error) {
 	logger := log.WithField("action", "find_form_elements")
+	page := b.query(ctx)
+	form = form.Context(ctx).Sleeper(rod.NotFoundSleeper)
 
 	selector := &Selector{form: form}
+	rules := b.rules()
 
 	// Find username input with retry
 	for i := 0; i < MaxRetries; i++ {
-		for _, sel := range userInputSelectors {
-			fmt.Println(sel)
-			if el, err := form.Element(sel); err == nil && el != nil {
+		for _, pattern := range rules.candidates(FieldUserInput) {
+			if el, err := pattern.find(form); err == nil && el != nil {
 				if visible, _ := el.Visible(); visible {
 					selector.UserInput = el.MustGetXPath(false)
+					selector.found(FieldUserInput, pattern)
 					logger.WithField("xpath", selector.UserInput).Debug("Found username input")
 					goto foundPass
 				}
 			}
 		}
 		if i < MaxRetries-1 {
-			time.Sleep(BackoffFactor * time.Duration(1<<uint(i)))
+			if err := sleep(ctx, BackoffFactor*time.Duration(1<<uint(i))); err != nil {
+				return nil, err
+			}
 			logger.WithField("attempt", i+1).Debug("Username input not found, retrying...")
 		}
 	}
@@ -194,18 +140,20 @@ func (b *Browser) findFormElements(form *rod.Element, enhance bool) (*Selector,
 foundPass:
 	// Find password input with retry
 	for i := 0; i < MaxRetries; i++ {
-		for _, sel := range passInputSelectors {
-			fmt.Println(sel)
-			if el, err := form.Element(sel); err == nil && el != nil {
+		for _, pattern := range rules.Patterns(FieldPasswordInput) {
+			if el, err := pattern.find(form); err == nil && el != nil {
 				if visible, _ := el.Visible(); visible {
 					selector.PasswordInput = el.MustGetXPath(false)
+					selector.found(FieldPasswordInput, pattern)
 					logger.WithField("xpath", selector.PasswordInput).Debug("Found password input")
 					goto foundButton
 				}
 			}
 		}
 		if i < MaxRetries-1 {
-			time.Sleep(BackoffFactor * time.Duration(1<<uint(i)))
+			if err := sleep(ctx, BackoffFactor*time.Duration(1<<uint(i))); err != nil {
+				return nil, err
+			}
 			logger.WithField("attempt", i+1).Debug("Password input not found, retrying...")
 		}
 	}
@@ -213,17 +161,20 @@ foundPass:
 foundButton:
 	// Find login button with retry
 	for i := 0; i < MaxRetries; i++ {
-		for _, sel := range loginBtnSelectors {
-			if el, err := form.Element(sel); err == nil && el != nil {
+		for _, pattern := range rules.candidates(FieldLoginBtn) {
+			if el, err := pattern.find(form); err == nil && el != nil {
 				if visible, _ := el.Visible(); visible {
 					selector.LoginBtn = el.MustGetXPath(false)
+					selector.found(FieldLoginBtn, pattern)
 					logger.WithField("xpath", selector.LoginBtn).Debug("Found login button")
 					goto foundRememberCheckBox
 				}
 			}
 		}
 		if i < MaxRetries-1 {
-			time.Sleep(BackoffFactor * time.Duration(1<<uint(i)))
+			if err := sleep(ctx, BackoffFactor*time.Duration(1<<uint(i))); err != nil {
+				return nil, err
+			}
 			logger.WithField("attempt", i+1).Debug("Login button not found, retrying...")
 		}
 	}
@@ -231,10 +182,11 @@ foundButton:
 	// enhance login button
 	if enhance {
 		logger.WithField("attempt", 0).Debug("Login button not found, enhance retrying...")
-		for _, sel := range loginBtnSelectors {
-			if el, err := b.page.Element(sel); err == nil && el != nil {
+		for _, pattern := range rules.Patterns(FieldLoginBtn) {
+			if el, err := pattern.find(page); err == nil && el != nil {
 				if visible, _ := el.Visible(); visible {
 					selector.LoginBtn = el.MustGetXPath(false)
+					selector.found(FieldLoginBtn, pattern)
 					logger.WithField("xpath", selector.LoginBtn).Debug("Enhance Found login button")
 					goto foundRememberCheckBox
 				}
@@ -244,13 +196,14 @@ foundButton:
 
 foundRememberCheckBox:
 	for i := 0; i < MaxRetries; i++ {
-		for _, sel := range checkBoxSelectors {
+		for _, pattern := range rules.Patterns(FieldRememberMe) {
 			// Find checkboxes (both remember me and agreement types)
-			checkboxes, err := form.Elements(sel)
+			checkboxes, err := pattern.findAll(form)
 			if err == nil && len(checkboxes) > 0 {
 				for _, checkbox := range checkboxes {
 					//if visible, _ := checkbox.Visible(); visible {}
 					selector.RememberMe = checkbox.MustGetXPath(false)
+					selector.found(FieldRememberMe, pattern)
 					logger.WithField("xpath", selector.RememberMe).Debug("Found rememberMe checkbox")
 					goto foundCaptchaInput
 				}
@@ -258,7 +211,9 @@ foundRememberCheckBox:
 		}
 
 		if i < MaxRetries-1 {
-			time.Sleep(BackoffFactor * time.Duration(1<<uint(i)))
+			if err := sleep(ctx, BackoffFactor*time.Duration(1<<uint(i))); err != nil {
+				return nil, err
+			}
 			logger.WithField("attempt", i+1).Debug("rememberMe checkbox not found, retrying...")
 		}
 	}
@@ -266,10 +221,11 @@ foundRememberCheckBox:
 foundCaptchaInput:
 	if b.captchaHandler != nil {
 		for i := 0; i < MaxRetries; i++ {
-			for _, sel := range captchaInputSelectors {
-				if el, err := form.Element(sel); err == nil && el != nil {
+			for _, pattern := range rules.Patterns(FieldCaptchaInput) {
+				if el, err := pattern.find(form); err == nil && el != nil {
 					if visible, _ := el.Visible(); visible {
 						selector.CaptchaInput = el.MustGetXPath(false)
+						selector.found(FieldCaptchaInput, pattern)
 						logger.WithField("xpath", selector.CaptchaInput).Debug("Found Captcha Input")
 						goto foundCaptchaImage
 					}
@@ -277,7 +233,9 @@ foundCaptchaInput:
 			}
 
 			if i < MaxRetries-1 {
-				time.Sleep(BackoffFactor * time.Duration(1<<uint(i)))
+				if err := sleep(ctx, BackoffFactor*time.Duration(1<<uint(i))); err != nil {
+					return nil, err
+				}
 				logger.WithField("attempt", i+1).Debug("captcha input not found, retrying...")
 			}
 		}
@@ -286,10 +244,11 @@ foundCaptchaInput:
 foundCaptchaImage:
 	if b.captchaHandler != nil && selector.CaptchaInput != "" {
 		for i := 0; i < MaxRetries; i++ {
-			for _, sel := range captchaImageSelectors {
-				if el, err := form.Element(sel); err == nil && el != nil {
+			for _, pattern := range rules.Patterns(FieldCaptchaImg) {
+				if el, err := pattern.find(form); err == nil && el != nil {
 					if visible, _ := el.Visible(); visible {
 						selector.CaptchaImg = el.MustGetXPath(false)
+						selector.found(FieldCaptchaImg, pattern)
 						logger.WithField("xpath", selector.CaptchaImg).Debug("Found Captcha Image")
 						goto over
 					}
@@ -297,7 +256,9 @@ foundCaptchaImage:
 			}
 
 			if i < MaxRetries-1 {
-				time.Sleep(BackoffFactor * time.Duration(1<<uint(i)))
+				if err := sleep(ctx, BackoffFactor*time.Duration(1<<uint(i))); err != nil {
+					return nil, err
+				}
 				logger.WithField("attempt", i+1).Debug("captcha image not found, retrying...")
 			}
 		}
@@ -309,218 +270,140 @@ over:
 		return selector, nil
 	}
 
-	return nil, fmt.Errorf("not form all elements found")
+	return selector, fmt.Errorf("not form all elements found")
 }
 
-// findFormElements
-// @Description: 匹配表单内元素
+// DetectForms
+// @Description: 为页面上的全部表单评分，按分数从高到低排列，分数相同时保持页面顺序；
+// 没有完整的表单时追加虚拟容器（见 DetectVirtualForms）
 // @receiver b
-// @param form
-// @return *Selector
+// @param ctx
+// @return []*FormDesc
 // @return error
-func (b *Browser) findElements() (*Selector, error) {
-	logger := log.WithField("action", "find_form_elements")
+func (b *Browser) DetectForms(ctx context.Context) ([]*FormDesc, error) {
+	logger := log.WithField("action", "detect_forms")
+	page := b.query(ctx)
 
-	selector := &Selector{}
-
-	// Find username input with retry
-	for i := 0; i < MaxRetries; i++ {
-		for _, sel := range userInputSelectors {
-			if el, err := b.page.Element(sel); err == nil && el != nil {
-				if visible, _ := el.Visible(); visible {
-					selector.UserInput = el.MustGetXPath(false)
-					logger.WithField("xpath", selector.UserInput).Debug("Found username input")
-					goto foundPass
-				}
-			}
-		}
-		if i < MaxRetries-1 {
-			time.Sleep(BackoffFactor * time.Duration(1<<uint(i)))
-			logger.WithField("attempt", i+1).Debug("Username input not found, retrying...")
-		}
+	forms, err := page.Elements("form")
+	if err != nil {
+		return nil, err
 	}
 
-foundPass:
-	// Find password input with retry
-	for i := 0; i < MaxRetries; i++ {
-		for _, sel := range passInputSelectors {
-			if el, err := b.page.Element(sel); err == nil && el != nil {
-				if visible, _ := el.Visible(); visible {
-					selector.PasswordInput = el.MustGetXPath(false)
-					logger.WithField("xpath", selector.PasswordInput).Debug("Found password input")
-					goto foundButton
-				}
+	var candidates []*FormDesc
+	for i, formEL := range forms {
+		desc, err := b.scoreLoginForm(ctx, formEL)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
 			}
+			logger.WithError(err).WithField("index", i).Debug("Failed to score form")
+			continue
 		}
-		if i < MaxRetries-1 {
-			time.Sleep(BackoffFactor * time.Duration(1<<uint(i)))
-			logger.WithField("attempt", i+1).Debug("Password input not found, retrying...")
-		}
+		desc.Index = i
+		candidates = append(candidates, desc)
 	}
 
-foundButton:
-	// Find login button with retry
-	fmt.Println(b.page.HTML())
-	for i := 0; i < MaxRetries; i++ {
-		for _, sel := range loginBtnSelectors {
-			if el, err := b.page.Element(sel); err == nil && el != nil {
-				if visible, _ := el.Visible(); visible {
-					selector.LoginBtn = el.MustGetXPath(false)
-					logger.WithField("xpath", selector.LoginBtn).Debug("Found login button")
-					goto foundRememberCheckBox
-				}
-			}
-		}
-		if i < MaxRetries-1 {
-			time.Sleep(BackoffFactor * time.Duration(1<<uint(i)))
-			logger.WithField("attempt", i+1).Debug("Login button not found, retrying...")
+	// 没有完整的表单时按密码框组成虚拟容器
+	if _, err := SelectForm(candidates, -1); err != nil {
+		logger.WithField("forms", len(forms)).Debug("No complete form, falling back to formless detection")
+		virtual, err := b.DetectVirtualForms(ctx, len(forms))
+		if err != nil {
+			return nil, err
 		}
+		candidates = append(candidates, virtual...)
 	}
 
-foundRememberCheckBox:
-	for i := 0; i < MaxRetries; i++ {
-		for _, sel := range checkBoxSelectors {
-			// Find checkboxes (both remember me and agreement types)
-			checkboxes, err := b.page.Elements(sel)
-			if err == nil && len(checkboxes) > 0 {
-				for _, checkbox := range checkboxes {
-					//if visible, _ := checkbox.Visible(); visible {}
-					selector.RememberMe = checkbox.MustGetXPath(false)
-					logger.WithField("xpath", selector.RememberMe).Debug("Found rememberMe checkbox")
-					goto foundCaptchaInput
-				}
-			}
-		}
+	// 得分排序
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
 
-		if i < MaxRetries-1 {
-			time.Sleep(BackoffFactor * time.Duration(1<<uint(i)))
-			logger.WithField("attempt", i+1).Debug("rememberMe checkbox not found, retrying...")
-		}
+	for _, c := range candidates {
+		logger.WithFields(log.Fields{
+			"index":      c.Index,
+			"score":      c.Score,
+			"confidence": c.Confidence,
+			"complete":   c.Complete,
+			"reasons":    c.Reasons,
+		}).Debug("Form scored")
 	}
+	return candidates, nil
+}
 
-foundCaptchaInput:
-	if b.captchaHandler != nil {
-		for i := 0; i < MaxRetries; i++ {
-			for _, sel := range captchaInputSelectors {
-				if el, err := b.page.Element(sel); err == nil && el != nil {
-					if visible, _ := el.Visible(); visible {
-						selector.CaptchaInput = el.MustGetXPath(false)
-						logger.WithField("xpath", selector.CaptchaInput).Debug("Found Captcha Input")
-						goto foundCaptchaImage
-					}
-				}
-			}
-
-			if i < MaxRetries-1 {
-				time.Sleep(BackoffFactor * time.Duration(1<<uint(i)))
-				logger.WithField("attempt", i+1).Debug("captcha input not found, retrying...")
-			}
+// SelectForm
+// @Description: 从排好序的候选表单中选择一个：index < 0 时选分数最高的完整表单，否则选页面中第 index 个表单
+// @param forms
+// @param index
+// @return *FormDesc
+// @return error
+func SelectForm(forms []*FormDesc, index int) (*FormDesc, error) {
+	for _, f := range forms {
+		if index >= 0 && f.Index != index {
+			continue
 		}
-	}
-
-foundCaptchaImage:
-	if b.captchaHandler != nil && selector.CaptchaInput != "" {
-		for i := 0; i < MaxRetries; i++ {
-			for _, sel := range captchaImageSelectors {
-				if el, err := b.page.Element(sel); err == nil && el != nil {
-					if visible, _ := el.Visible(); visible {
-						selector.CaptchaImg = el.MustGetXPath(false)
-						logger.WithField("xpath", selector.CaptchaImg).Debug("Found Captcha Image")
-						goto over
-					}
-				}
-			}
-
-			if i < MaxRetries-1 {
-				time.Sleep(BackoffFactor * time.Duration(1<<uint(i)))
-				logger.WithField("attempt", i+1).Debug("captcha image not found, retrying...")
-			}
+		if f.Complete {
+			return f, nil
+		}
+		if index >= 0 {
+			return nil, fmt.Errorf("%w: form %d has no complete login fields", ErrFormNotFound, index)
 		}
 	}
-
-over:
-	//return selector, nil
-	if selector.UserInput != "" && selector.PasswordInput != "" && selector.LoginBtn != "" {
-		return selector, nil
+	if index >= 0 {
+		return nil, fmt.Errorf("%w: no form at index %d among %d forms", ErrFormNotFound, index, len(forms))
 	}
+	return nil, fmt.Errorf("%w: no visible form or login fields found", ErrFormNotFound)
+}
 
-	return nil, fmt.Errorf("not form all elements found")
+// Selector
+// @Description: 表单的选择器，表单不完整时为 nil
+// @receiver f
+// @return *Selector
+func (f *FormDesc) Selector() *Selector {
+	return f.selector
 }
 
 // DetectFormSelectors
-// @Description: 自动探测Form表单以及内部相关的其他标签元素
+// @Description: 自动探测Form表单以及内部相关的其他标签元素，选择分数最高的完整表单
 // @receiver b
 // @return *Selector
 // @return error
-func (b *Browser) DetectFormSelectors() (*Selector, error) {
+func (b *Browser) DetectFormSelectors(ctx context.Context) (*Selector, error) {
 	logger := log.WithField("action", "detect_form_and_selectors")
 	logger.Debug("Starting selector detection")
 
-	var err error
-	var s *Selector
-
-	//s, err = b.findElements(b.page)
-	//if err != nil {
-	//	return nil, fmt.Errorf("no visible form found")
-	//}
-
-	var formScores []*FormDesc
-	var forms rod.Elements
-	var formEL *rod.Element
-
-	if forms, err = b.page.Elements("form"); err == nil {
-		if len(forms) > 0 && forms != nil {
-			// 多form情况
-			for _, formEL = range forms {
-				score, formErr := b.scoreLoginForm(formEL)
-				if formErr == nil {
-					formScores = append(formScores, score)
-				}
-			}
-		} else {
-			// 单form情况
-			if formEL, err = b.page.Element("form"); err == nil && formEL != nil {
-				if visible, _ := formEL.Visible(); visible {
-					score, formErr := b.scoreLoginForm(formEL)
-					if formErr == nil {
-						formScores = append(formScores, score)
-					}
-				}
-			}
-		}
+	forms, err := b.DetectForms(ctx)
+	if err != nil {
+		return nil, err
 	}
-
-	if len(formScores) == 0 {
-		// 没匹配到表单的话则进行结束
-		return nil, fmt.Errorf("no visible form found")
+	form, err := SelectForm(forms, -1)
+	if err != nil {
+		return nil, err
 	}
+	LogForm(form)
+	return form.Selector(), nil
+}
 
-	formEL = formScores[0].Form
-	s = formScores[0].selector
-
-	// 得分排序
-	sort.Slice(formScores, func(i, j int) bool {
-		return formScores[i].Score > formScores[j].Score
+// LogForm
+// @Description: 输出选中表单的信息，置信度低时提示
+// @param form
+func LogForm(form *FormDesc) {
+	logger := log.WithFields(log.Fields{
+		"action":     "detect_form_and_selectors",
+		"index":      form.Index,
+		"virtual":    form.Virtual,
+		"id":         form.ID,
+		"formAction": form.Action,
+		"method":     form.Method,
+		"position":   form.Position,
+		"score":      form.Score,
+		"confidence": form.Confidence,
+		"hasLogin":   form.HasLogin,
+		"hasPass":    form.HasPass,
+		"hasSubmit":  form.HasSubmit,
 	})
-
-	// 匹配到表单的话则进行打印信息
-	formDetails := map[string]interface{}{
-		"tag":      formEL.MustEval("() => this.tagName ").String(),
-		"id":       formEL.MustEval("() => this.id ").String(),
-		"action":   formEL.MustEval("() => this.action ").String(),
-		"method":   formEL.MustEval("() => this.method ").String(),
-		"position": formEL.MustShape().Box(),
+	if form.Confidence < LowConfidence {
+		logger.WithField("reasons", form.Reasons).Warn("Login form detected with low confidence")
+		return
 	}
-
-	logger.WithFields(log.Fields{"formDetails": formDetails}).Info("Form Details")
-	logger.WithFields(log.Fields{
-		"score":       formScores[0].Score,
-		"hasLogin":    formScores[0].HasLogin,
-		"hasPass":     formScores[0].HasPass,
-		"hasSubmit":   formScores[0].HasSubmit,
-		"position":    formScores[0].Position,
-		"formDetails": formDetails,
-	}).Debug("Form scored with details")
-
-	return s, nil
+	logger.Info("Form Details")
 }
@@ -0,0 +1,64 @@
+package browser
+
+import (
+	"context"
+)
+
+// CandidateReport
+// @Description: 单个候选选择器在页面上的匹配情况
+type CandidateReport struct {
+	Selector string `json:"selector"`
+	Matches  int    `json:"matches"`
+	Visible  int    `json:"visible"`
+	OK       bool   `json:"ok"`
+	Error    string `json:"error,omitempty"`
+}
+
+// FieldReport
+// @Description: 单个字段的匹配情况，任一候选恰好匹配一个可见元素即视为通过
+type FieldReport struct {
+	Field      string            `json:"field"`
+	Candidates []CandidateReport `json:"candidates"`
+	OK         bool              `json:"ok"`
+}
+
+// ValidateSelector
+// @Description: 在当前页面上检查各字段的候选选择器是否恰好匹配一个可见元素，未配置的可选字段跳过
+// @receiver b
+// @param ctx
+// @param selector
+// @return []FieldReport
+func (b *Browser) ValidateSelector(ctx context.Context, selector *Selector) []FieldReport {
+	// 等待页面渲染出用户名输入框（单页应用），找不到也继续统计
+	_, _ = b.findField(ctx, selector, FieldUserInput, "user input")
+
+	var reports []FieldReport
+	for _, name := range SelectorFields {
+		candidates := selector.Candidates(name)
+		if len(candidates) == 0 {
+			continue
+		}
+
+		report := FieldReport{Field: name}
+		for _, raw := range candidates {
+			c := CandidateReport{Selector: raw}
+
+			els, err := b.resolveAll(ctx, raw)
+			if err != nil {
+				c.Error = err.Error()
+			}
+			c.Matches = len(els)
+			for _, el := range els {
+				if visible, err := el.Visible(); err == nil && visible {
+					c.Visible++
+				}
+			}
+			c.OK = c.Error == "" && c.Visible == 1
+
+			report.OK = report.OK || c.OK
+			report.Candidates = append(report.Candidates, c)
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
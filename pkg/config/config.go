@@ -1,40 +1,275 @@
 package config
 
-import "time"
-
-type SelectorConfig struct {
-	UserInput     string `yaml:"userInput"`
-	PasswordInput string `yaml:"passwordInput"`
-	LoginBtn      string `yaml:"loginBtn"`
-	CaptchaInput  string `yaml:"captchaInput"`
-	CaptchaImg    string `yaml:"captchaImg"`
-}
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"xiaoyu/pkg/browser"
+	"xiaoyu/pkg/creds"
+)
+
+// EnvPrefix 环境变量前缀，如 WEBLOGIN_PROXY
+const EnvPrefix = "WEBLOGIN_"
 
+const (
+	DefaultLogLevel          = "debug"
+	DefaultOutputFile        = "output.json"
+	DefaultThreads           = 3
+	DefaultHostThreads       = 1
+	DefaultDelay             = 1
+	DefaultMaxAttempts       = 3
+	DefaultMaxCrackTime      = 300
+	DefaultNavigationTimeout = 10
+	DefaultElementTimeout    = 5
+	DefaultLoginTimeout      = 15
+	DefaultOCRURL            = "http://120.26.57.12:8000"
+	DefaultBaselineThreshold = browser.DefaultBaselineThreshold
+	DefaultLockoutAction     = "skip-user"
+	DefaultLockoutPause      = 300
+	DefaultCooldown          = 300
+	DefaultStrategy          = "user"
+	DefaultStopOn            = "user"
+	DefaultSprayWait         = 1800
+	DefaultFormIndex         = -1
+)
+
+// Config
+// @Description: 一次运行的完整配置，优先级：配置文件 < 环境变量 < 命令行参数。
+// 时间类字段单位除 Jitter（毫秒）外均为秒。
 type Config struct {
-	Inputs        []string
-	InputsFile    string
-	LogLevel      string
-	OutputFile    string
-	CrackAll      bool
-	Delay         int
-	Headless      bool
-	MaxAttempts   int
-	MaxCrackNum   int
-	MaxCrackTime  int
-	PassList      []string
-	PassFile      string
-	Proxy         string
-	SelectorFile  string
-	Threads       int
-	UserList      []string
-	UserFile      string
-	LoginTimeout  time.Duration
-	PageTimeout   time.Duration
+	// 输入
+	Inputs       []string          `yaml:"inputs" json:"inputs" env:"INPUTS"`
+	InputsFile   string            `yaml:"inputsFile" json:"inputsFile" env:"INPUTS_FILE"`
+	UserList     []string          `yaml:"users" json:"users" env:"USERS"`
+	UserFile     string            `yaml:"userFile" json:"userFile" env:"USER_FILE"`
+	PassList     []string          `yaml:"passwords" json:"passwords" env:"PASSWORDS"`
+	PassFile     string            `yaml:"passFile" json:"passFile" env:"PASS_FILE"`
+	ComboFile    string            `yaml:"comboFile" json:"comboFile" env:"COMBO_FILE"`
+	Combos       []creds.Pair      `yaml:"-" json:"-"`
+	DefaultCreds string            `yaml:"defaultCreds" json:"defaultCreds" env:"DEFAULT_CREDS"`
+	Rules        string            `yaml:"rules" json:"rules" env:"RULES"`
+	Company      string            `yaml:"company" json:"company" env:"COMPANY"`
+	SelectorFile string            `yaml:"selectorFile" json:"selectorFile" env:"SELECTOR_FILE"`
+	Fingerprints string            `yaml:"fingerprints" json:"fingerprints" env:"FINGERPRINTS"`
+	DetectRules  string            `yaml:"detectRules" json:"detectRules" env:"DETECT_RULES"`
+	FormIndex    int               `yaml:"formIndex" json:"formIndex" env:"FORM_INDEX"`
+	Selector     *browser.Selector `yaml:"selector" json:"selector,omitempty"`
+	TargetsFile  string            `yaml:"targetsFile" json:"targetsFile" env:"TARGETS_FILE"`
+	Targets      []*Target         `yaml:"targets" json:"targets,omitempty"`
+
+	// 输出
+	LogLevel   string `yaml:"logLevel" json:"logLevel" env:"LOG_LEVEL"`
+	OutputFile string `yaml:"outputFile" json:"outputFile" env:"OUTPUT_FILE"`
+	StreamFile string `yaml:"streamFile" json:"streamFile" env:"STREAM_FILE"`
+	StateFile  string `yaml:"stateFile" json:"stateFile" env:"STATE_FILE"`
+	Resume     bool   `yaml:"resume" json:"resume" env:"RESUME"`
+
+	// 浏览器
+	Headless    bool   `yaml:"headless" json:"headless" env:"HEADLESS"`
+	Proxy       string `yaml:"proxy" json:"proxy" env:"PROXY"`
+	OCRURL      string `yaml:"ocrURL" json:"ocrURL" env:"OCR_URL"`
+	DetectOnly  bool   `yaml:"detectOnly" json:"detectOnly" env:"DETECT_ONLY"`
+	DryRun      bool   `yaml:"dryRun" json:"dryRun" env:"DRY_RUN"`
+	Threads     int    `yaml:"threads" json:"threads" env:"THREADS"`
+	HostThreads int    `yaml:"hostThreads" json:"hostThreads" env:"HOST_THREADS"`
+
+	// 网络观察：登录请求的状态码、JSON 响应和令牌参与登录判定
+	ObserveNetwork bool `yaml:"observeNetwork" json:"observeNetwork" env:"OBSERVE_NETWORK"`
+
+	// 爆破
+	CrackAll     bool `yaml:"crackAll" json:"crackAll" env:"CRACK_ALL"`
+	Delay        int  `yaml:"delay" json:"delay" env:"DELAY"`
+	MaxAttempts  int  `yaml:"maxAttempts" json:"maxAttempts" env:"MAX_ATTEMPTS"`
+	MaxCrackNum  int  `yaml:"maxCrackNum" json:"maxCrackNum" env:"MAX_CRACK_NUM"`
+	MaxCrackTime int  `yaml:"maxCrackTime" json:"maxCrackTime" env:"MAX_CRACK_TIME"`
+
+	// 停止条件：每个用户最多尝试次数、成功后停止的范围（target|user|never）、全局成功次数上限；0 表示不限制
+	MaxUserCrackNum int    `yaml:"maxUserCrackNum" json:"maxUserCrackNum" env:"MAX_USER_CRACK_NUM"`
+	StopOn          string `yaml:"stopOn" json:"stopOn" env:"STOP_ON"`
+	MaxSuccess      int    `yaml:"maxSuccess" json:"maxSuccess" env:"MAX_SUCCESS"`
+
+	// 执行顺序：user 按用户，spray 一个密码试完所有用户后等待 SprayWait 秒再试下一个密码
+	Strategy  string `yaml:"strategy" json:"strategy" env:"STRATEGY"`
+	SprayWait int    `yaml:"sprayWait" json:"sprayWait" env:"SPRAY_WAIT"`
+
+	// 频率限制：每个主机每分钟最多 Rate 次尝试，每次附加 [0, Jitter) 毫秒的随机等待，
+	// 同一账号连续失败 CooldownAfter 次后冷却 Cooldown 秒；0 表示不限制
+	Rate          int `yaml:"rate" json:"rate" env:"RATE"`
+	Jitter        int `yaml:"jitter" json:"jitter" env:"JITTER"`
+	CooldownAfter int `yaml:"cooldownAfter" json:"cooldownAfter" env:"COOLDOWN_AFTER"`
+	Cooldown      int `yaml:"cooldown" json:"cooldown" env:"COOLDOWN"`
+
+	// 失败基线：先用随机凭证提交一次，之后按与该结果的相似度判定
+	Baseline          bool    `yaml:"baseline" json:"baseline" env:"BASELINE"`
+	BaselineThreshold float64 `yaml:"baselineThreshold" json:"baselineThreshold" env:"BASELINE_THRESHOLD"`
+
+	// 锁定检测：命中提示短语、429/423 或突然出现验证码时按 LockoutAction 处理
+	LockoutPhrases string `yaml:"lockoutPhrases" json:"lockoutPhrases" env:"LOCKOUT_PHRASES"`
+	LockoutAction  string `yaml:"lockoutAction" json:"lockoutAction" env:"LOCKOUT_ACTION"`
+	LockoutPause   int    `yaml:"lockoutPause" json:"lockoutPause" env:"LOCKOUT_PAUSE"`
+
+	// 超时
+	NavigationTimeout int `yaml:"navigationTimeout" json:"navigationTimeout" env:"NAVIGATION_TIMEOUT"`
+	ElementTimeout    int `yaml:"elementTimeout" json:"elementTimeout" env:"ELEMENT_TIMEOUT"`
+	LoginTimeout      int `yaml:"loginTimeout" json:"loginTimeout" env:"LOGIN_TIMEOUT"`
 }
 
 func NewConfig() *Config {
 	return &Config{
-		LoginTimeout: DefaultLoginTimeout,
-		PageTimeout:  DefaultPageTimeout,
+		LogLevel:          DefaultLogLevel,
+		OutputFile:        DefaultOutputFile,
+		OCRURL:            DefaultOCRURL,
+		Threads:           DefaultThreads,
+		HostThreads:       DefaultHostThreads,
+		Delay:             DefaultDelay,
+		MaxAttempts:       DefaultMaxAttempts,
+		MaxCrackTime:      DefaultMaxCrackTime,
+		BaselineThreshold: DefaultBaselineThreshold,
+		LockoutAction:     DefaultLockoutAction,
+		LockoutPause:      DefaultLockoutPause,
+		Cooldown:          DefaultCooldown,
+		Strategy:          DefaultStrategy,
+		StopOn:            DefaultStopOn,
+		SprayWait:         DefaultSprayWait,
+		FormIndex:         DefaultFormIndex,
+		NavigationTimeout: DefaultNavigationTimeout,
+		ElementTimeout:    DefaultElementTimeout,
+		LoginTimeout:      DefaultLoginTimeout,
+	}
+}
+
+// LoadFile
+// @Description: 从 YAML 或 JSON 文件加载配置，文件中未出现的字段保持原值
+// @receiver c
+// @param path
+// @return error
+func (c *Config) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err = decoder.Decode(c); err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		return nil
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// ApplyEnv
+// @Description: 使用环境变量覆盖配置，变量名为 EnvPrefix 加字段的 env 标签，列表以逗号分隔
+// @receiver c
+// @param lookup 一般传入 os.LookupEnv
+// @return error
+func (c *Config) ApplyEnv(lookup func(string) (string, bool)) error {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		raw, ok := lookup(EnvPrefix + name)
+		if !ok {
+			continue
+		}
+
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(raw)
+		case reflect.Int:
+			n, err := strconv.Atoi(strings.TrimSpace(raw))
+			if err != nil {
+				return fmt.Errorf("invalid value for %s%s: %w", EnvPrefix, name, err)
+			}
+			field.SetInt(int64(n))
+		case reflect.Float64:
+			f, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+			if err != nil {
+				return fmt.Errorf("invalid value for %s%s: %w", EnvPrefix, name, err)
+			}
+			field.SetFloat(f)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(strings.TrimSpace(raw))
+			if err != nil {
+				return fmt.Errorf("invalid value for %s%s: %w", EnvPrefix, name, err)
+			}
+			field.SetBool(b)
+		case reflect.Slice:
+			var items []string
+			for _, item := range strings.Split(raw, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			field.Set(reflect.ValueOf(items))
+		}
+	}
+	return nil
+}
+
+// redacted 脱敏后的占位值
+const redacted = "<redacted>"
+
+// Redacted
+// @Description: 用于输出日志的副本：去掉用户名和密码列表（含各目标中的），隐藏请求头的值和代理中的账号密码
+// @receiver c
+// @return *Config
+func (c *Config) Redacted() *Config {
+	out := *c
+	out.UserList, out.PassList, out.Combos = nil, nil, nil
+	out.Proxy = redactProxy(c.Proxy)
+
+	out.Targets = make([]*Target, 0, len(c.Targets))
+	for _, t := range c.Targets {
+		if t == nil {
+			continue
+		}
+		copied := *t
+		copied.Users, copied.Passwords, copied.Combos = nil, nil, nil
+		copied.Proxy = redactProxy(t.Proxy)
+		if len(t.Headers) > 0 {
+			copied.Headers = make(map[string]string, len(t.Headers))
+			for k := range t.Headers {
+				copied.Headers[k] = redacted
+			}
+		}
+		out.Targets = append(out.Targets, &copied)
+	}
+	if len(out.Targets) == 0 {
+		out.Targets = nil
+	}
+	return &out
+}
+
+// redactProxy 隐藏代理地址中的账号密码
+func redactProxy(proxy string) string {
+	u, err := url.Parse(proxy)
+	if err != nil || u.User == nil {
+		return proxy
 	}
+	u.User = url.User(redacted)
+	return u.String()
 }
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"xiaoyu/pkg/browser"
+	"xiaoyu/pkg/creds"
+)
+
+// Target
+// @Description: 清单中的单个目标，未配置的字段继承全局配置
+type Target struct {
+	URL               string            `yaml:"url" json:"url"`
+	Notes             string            `yaml:"notes" json:"notes,omitempty"`
+	Company           string            `yaml:"company" json:"company,omitempty"`
+	SelectorFile      string            `yaml:"selectorFile" json:"selectorFile,omitempty"`
+	Selector          *browser.Selector `yaml:"selector" json:"selector,omitempty"`
+	Users             []string          `yaml:"users" json:"users,omitempty"`
+	UserFile          string            `yaml:"userFile" json:"userFile,omitempty"`
+	Passwords         []string          `yaml:"passwords" json:"passwords,omitempty"`
+	PassFile          string            `yaml:"passFile" json:"passFile,omitempty"`
+	ComboFile         string            `yaml:"comboFile" json:"comboFile,omitempty"`
+	Combos            []creds.Pair      `yaml:"-" json:"-"`
+	Proxy             string            `yaml:"proxy" json:"proxy,omitempty"`
+	Headers           map[string]string `yaml:"headers" json:"headers,omitempty"`
+	NavigationTimeout int               `yaml:"navigationTimeout" json:"navigationTimeout,omitempty"`
+	ElementTimeout    int               `yaml:"elementTimeout" json:"elementTimeout,omitempty"`
+	LoginTimeout      int               `yaml:"loginTimeout" json:"loginTimeout,omitempty"`
+	FormIndex         *int              `yaml:"formIndex" json:"formIndex,omitempty"` // 页面中第几个表单，nil 时继承全局配置
+}
+
+// manifest 清单文件既可以是 targets 列表，也可以直接是顶层列表
+type manifest struct {
+	Targets []*Target `yaml:"targets" json:"targets"`
+}
+
+// LoadTargets
+// @Description: 从 YAML 或 JSON 清单文件加载目标
+// @param path
+// @return []*Target
+// @return error
+func LoadTargets(path string) ([]*Target, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read targets file: %w", err)
+	}
+
+	var targets []*Target
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		trimmed := bytes.TrimSpace(data)
+		decoder := json.NewDecoder(bytes.NewReader(trimmed))
+		decoder.DisallowUnknownFields()
+		if len(trimmed) > 0 && trimmed[0] == '[' {
+			err = decoder.Decode(&targets)
+		} else {
+			var m manifest
+			err = decoder.Decode(&m)
+			targets = m.Targets
+		}
+	} else {
+		var node yaml.Node
+		if err = yaml.Unmarshal(data, &node); err == nil && len(node.Content) > 0 && node.Content[0].Kind == yaml.SequenceNode {
+			decoder := yaml.NewDecoder(bytes.NewReader(data))
+			decoder.KnownFields(true)
+			err = decoder.Decode(&targets)
+		} else if err == nil {
+			var m manifest
+			decoder := yaml.NewDecoder(bytes.NewReader(data))
+			decoder.KnownFields(true)
+			err = decoder.Decode(&m)
+			targets = m.Targets
+		}
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse targets file %s: %w", path, err)
+	}
+
+	for i, t := range targets {
+		if t == nil || strings.TrimSpace(t.URL) == "" {
+			return nil, fmt.Errorf("targets file %s: entry %d has no url", path, i+1)
+		}
+	}
+	return targets, nil
+}
+
+// Inherit
+// @Description: 使用全局配置补全目标中未配置的字段
+// @receiver t
+// @param c
+func (t *Target) Inherit(c *Config) {
+	t.URL = strings.TrimSpace(t.URL)
+	if t.SelectorFile == "" && t.Selector == nil {
+		t.SelectorFile = c.SelectorFile
+		t.Selector = c.Selector
+	}
+	if len(t.Users) == 0 && t.UserFile == "" {
+		t.Users = c.UserList
+	}
+	if len(t.Passwords) == 0 && t.PassFile == "" {
+		t.Passwords = c.PassList
+	}
+	if t.ComboFile == "" {
+		t.Combos = c.Combos
+	}
+	if t.Proxy == "" {
+		t.Proxy = c.Proxy
+	}
+	if t.Company == "" {
+		t.Company = c.Company
+	}
+	if t.NavigationTimeout <= 0 {
+		t.NavigationTimeout = c.NavigationTimeout
+	}
+	if t.ElementTimeout <= 0 {
+		t.ElementTimeout = c.ElementTimeout
+	}
+	if t.LoginTimeout <= 0 {
+		t.LoginTimeout = c.LoginTimeout
+	}
+	if t.FormIndex == nil {
+		index := c.FormIndex
+		t.FormIndex = &index
+	}
+}
@@ -2,223 +2,476 @@ package crack
 
 import (
 	"context"
-	"strings"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"time"
 
 	"fmt"
 	log "github.com/sirupsen/logrus"
 	"xiaoyu/pkg/browser"
+	"xiaoyu/pkg/lockout"
+	"xiaoyu/pkg/pool"
+	"xiaoyu/pkg/ratelimit"
 )
 
 type Task struct {
-	URL      string
-	Username string
-	Password string
-	Timeout  int
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Timeout  int    `json:"timeout,omitempty"`
 }
 
+// Result
+// @Description: 一次登录尝试的结果。Error 保留原始错误链供 errors.Is 使用，序列化时输出 Code 与 Message
 type Result struct {
-	Success  bool
-	Error    error
-	Task     Task
-	Attempts int
+	Success  bool              `json:"success"`
+	Code     Outcome           `json:"code"`
+	Message  string            `json:"message"`
+	Error    error             `json:"-"`
+	Task     Task              `json:"task"`
+	Attempts int               `json:"attempts"`
+	Limits   Limits            `json:"limits"`
+	Matched  map[string]string `json:"matched,omitempty"` // 各字段实际命中的候选选择器
+	Rule     string            `json:"rule,omitempty"`    // 判定成功或失败时命中的规则
+	Tokens   map[string]string `json:"tokens,omitempty"`  // 开启网络观察时，登录成功后下发的认证令牌
+
+	Similarity float64 `json:"similarity,omitempty"` // 设置了失败基线时，与基线的相似度
+	Lockout    string  `json:"lockout,omitempty"`    // 检测到锁定时的原因
+}
+
+// Limits
+// @Description: 本次尝试实际生效的超时（秒）与重试次数
+type Limits struct {
+	NavigationTimeout float64 `json:"navigationTimeout"`
+	ElementTimeout    float64 `json:"elementTimeout"`
+	LoginTimeout      float64 `json:"loginTimeout"`
+	MaxAttempts       int     `json:"maxAttempts"`
+}
+
+// Checkpoint
+// @Description: 断点续跑接口，用于跳过已完成的尝试并记录新完成的尝试
+type Checkpoint interface {
+	IsDone(task Task) bool
+	MarkDone(result Result) error
 }
 
 type Cracker struct {
-	delay        time.Duration
-	maxAttempts  int
-	maxCrackNum  int
-	maxCrackTime time.Duration
-	threads      int
-	browser      *browser.Browser
-	selector     *browser.Selector
+	delay       time.Duration
+	maxAttempts int
+	browser     *browser.Browser
+	selector    *browser.Selector
+	timeouts    browser.Timeouts
+	onResult    func(Result)
+	checkpoint  Checkpoint
+	guard       *lockout.Guard
+	limiter     *ratelimit.Limiter
+	stopper     *Stopper
+	launch      func() (*browser.Browser, error)
+	baseline    *browser.Snapshot
+	threshold   float64
+	captchaSeen bool // 开始时已有验证码（或选择器配置了验证码），不作为锁定信号
+	loaded      bool // 已经打开过登录页，captchaSeen 已确定
 }
 
-func New(delay int, maxAttempts int, maxCrackNum int, maxCrackTime int, threads int, b *browser.Browser, s *browser.Selector) *Cracker {
+func New(delay int, maxAttempts int, b *browser.Browser, s *browser.Selector) *Cracker {
 	return &Cracker{
-		delay:        time.Duration(delay) * time.Second,
-		maxAttempts:  maxAttempts,
-		maxCrackNum:  maxCrackNum,
-		maxCrackTime: time.Duration(maxCrackTime) * time.Second,
-		threads:      threads,
-		browser:      b,
-		selector:     s,
+		delay:       time.Duration(delay) * time.Second,
+		maxAttempts: maxAttempts,
+		browser:     b,
+		selector:    s,
+		timeouts:    browser.DefaultTimeouts(),
 	}
 }
 
-func ProcessPassword(password string, username string) string {
-	return strings.ReplaceAll(password, "%user%", username)
+// SetTimeouts
+// @Description: 设置各阶段超时，Login 同时作为单次登录尝试的总超时
+// @receiver c
+// @param t
+func (c *Cracker) SetTimeouts(t browser.Timeouts) {
+	if t.Navigation > 0 {
+		c.timeouts.Navigation = t.Navigation
+	}
+	if t.Element > 0 {
+		c.timeouts.Element = t.Element
+	}
+	if t.Login > 0 {
+		c.timeouts.Login = t.Login
+	}
 }
 
-func (c *Cracker) SingleTaskCrack(ctx context.Context, task Task) []Result {
+// Limits
+// @Description: 当前生效的限制
+// @receiver c
+// @return Limits
+func (c *Cracker) Limits() Limits {
+	maxAttempts := c.maxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+	return Limits{
+		NavigationTimeout: c.timeouts.Navigation.Seconds(),
+		ElementTimeout:    c.timeouts.Element.Seconds(),
+		LoginTimeout:      c.timeouts.Login.Seconds(),
+		MaxAttempts:       maxAttempts,
+	}
+}
+
+// OnResult
+// @Description: 注册每次登录尝试结束后的回调，用于流式输出结果
+// @receiver c
+// @param fn
+func (c *Cracker) OnResult(fn func(Result)) {
+	c.onResult = fn
+}
+
+// SetCheckpoint
+// @Description: 设置断点续跑状态
+// @receiver c
+// @param cp
+func (c *Cracker) SetCheckpoint(cp Checkpoint) {
+	c.checkpoint = cp
+}
+
+// SetLockout
+// @Description: 设置共享的锁定检测，检测到锁定时按 guard 的处理方式暂停或放弃
+// @receiver c
+// @param g
+func (c *Cracker) SetLockout(g *lockout.Guard) {
+	c.guard = g
+}
+
+// SetRateLimit
+// @Description: 设置共享的频率限制，每次尝试前按主机取令牌，账号连续失败后冷却
+// @receiver c
+// @param l
+func (c *Cracker) SetRateLimit(l *ratelimit.Limiter) {
+	c.limiter = l
+}
+
+// SetStopper
+// @Description: 设置共享的停止条件，未设置时每个用户在首次成功后停止
+// @receiver c
+// @param s
+func (c *Cracker) SetStopper(s *Stopper) {
+	c.stopper = s
+}
+
+// SetLauncher
+// @Description: 设置按需启动浏览器的函数，New 未传入浏览器时在通过锁定暂停和频率限制后才启动，
+// 避免等待期间占用浏览器进程。启动的浏览器由调用方关闭
+// @receiver c
+// @param launch
+func (c *Cracker) SetLauncher(launch func() (*browser.Browser, error)) {
+	c.launch = launch
+}
+
+// RecordBaseline
+// @Description: 用随机的、必然无效的凭证提交一次登录，记录失败基线。调用前需已打开登录页
+// @receiver c
+// @param ctx
+// @return *browser.Snapshot
+// @return error
+func (c *Cracker) RecordBaseline(ctx context.Context) (*browser.Snapshot, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeouts.Login)
+	defer cancel()
 
-	var passwords []string
-	if task.Password != "" {
-		passwords = []string{task.Password}
-	} else {
-		passwords = []string{"123456", "1q2w3e4r", "12345", "Aa123456", "admin", "admin123", "admin@123", "Admin@123"}
+	username, password := randomCredential("wl_"), randomCredential("Wl#")
+	snapshot, err := c.browser.SubmitSnapshot(ctx, c.selector, username, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record baseline: %w", err)
 	}
+	return snapshot, nil
+}
+
+// SetBaseline
+// @Description: 设置失败基线，之后的每次尝试按与基线的相似度判定
+// @receiver c
+// @param snapshot
+// @param threshold 相似度不低于该值视为失败
+func (c *Cracker) SetBaseline(snapshot *browser.Snapshot, threshold float64) {
+	c.baseline, c.threshold = snapshot, threshold
+	if snapshot != nil && c.browser != nil {
+		c.browser.SetBaseline(snapshot, threshold)
+	}
+}
 
+// open
+// @Description: 浏览器尚未启动时通过 launcher 启动，并设置失败基线
+// @receiver c
+// @return error
+func (c *Cracker) open() error {
+	if c.browser != nil {
+		return nil
+	}
+	if c.launch == nil {
+		return errors.New("no browser or launcher set")
+	}
+	b, err := c.launch()
+	if err != nil {
+		return err
+	}
+	c.browser = b
+	if c.baseline != nil {
+		b.SetBaseline(c.baseline, c.threshold)
+	}
+	return nil
+}
+
+// load
+// @Description: 打开登录页，首次打开时记录页面上是否已有验证码
+// @receiver c
+// @param ctx
+// @param url
+// @return error
+func (c *Cracker) load(ctx context.Context, url string) error {
+	navigateCtx, cancel := context.WithTimeout(ctx, c.timeouts.Navigation)
+	err := c.browser.Navigate(navigateCtx, url)
+	cancel()
+	if err != nil {
+		return err
+	}
+	if c.guard != nil && !c.loaded {
+		c.captchaSeen = (c.selector != nil && c.selector.CaptchaImg != "") || c.browser.HasCaptcha(ctx)
+	}
+	c.loaded = true
+	return nil
+}
+
+// randomCredential 生成随机凭证
+func randomCredential(prefix string) string {
+	buf := make([]byte, 6)
+	_, _ = rand.Read(buf)
+	return prefix + hex.EncodeToString(buf)
+}
+
+// SingleTaskCrack
+// @Description: 尝试任务中的凭证，未指定密码时的默认凭证已由 CreateTasks 按产品展开为多个任务
+// @receiver c
+// @param ctx
+// @param task
+// @return []Result 被跳过时为空；锁定暂停后重试时包含被打断的尝试
+func (c *Cracker) SingleTaskCrack(ctx context.Context, task Task) []Result {
 	log.WithFields(log.Fields{
-		"action": "start_password_tests",
-		"target": task.URL,
-		"count":  len(passwords),
-	}).Info("Starting password tests")
+		"action":   "start_password_test",
+		"target":   task.URL,
+		"username": task.Username,
+	}).Info("Starting password test")
+
+	host := pool.HostOf(task.URL)
 
 	var results []Result
-	for _, pass := range passwords {
-		select {
-		case <-ctx.Done():
+	for {
+		// 该用户处于锁定暂停期间时，等待暂停结束后再尝试
+		if err := c.guard.WaitUntilClear(ctx, host, task.Username); err != nil {
 			return results
-		default:
-			_task := Task{
-				URL:      task.URL,
-				Username: task.Username,
-				Password: pass,
-			}
+		}
 
-			result := c.processTask(ctx, _task)
-			results = append(results, result)
+		// 该用户或主机已因锁定被放弃
+		if reason, blocked := c.guard.Blocked(host, task.Username); blocked {
+			log.WithFields(log.Fields{
+				"url":      task.URL,
+				"username": task.Username,
+				"reason":   reason,
+			}).Info("Skipping locked out user")
+			return results
+		}
 
-			if result.Success {
-				log.WithFields(log.Fields{
-					"url":      _task.URL,
-					"username": _task.Username,
-					"password": _task.Password,
-					"status":   "success",
-				}).Info("Login successful")
-				return results
+		// 跳过上次已完成的尝试
+		if c.checkpoint != nil && c.checkpoint.IsDone(task) {
+			log.WithFields(log.Fields{
+				"url":      task.URL,
+				"username": task.Username,
+				"password": task.Password,
+			}).Debug("Skipping finished attempt")
+			return results
+		}
+
+		// 停止条件，同时预占尝试次数
+		if reason, ok := c.stopper.Allow(task); !ok {
+			log.WithFields(log.Fields{
+				"url":      task.URL,
+				"username": task.Username,
+				"reason":   reason,
+			}).Debug("Stop condition reached")
+			return results
+		}
+
+		// 频率限制，可被取消
+		if err := c.limiter.Wait(ctx, host, task.Username); err != nil {
+			return results
+		}
+
+		// 通过等待后才启动浏览器，processTask 每次尝试前重新打开登录页
+		if err := c.open(); err != nil {
+			log.WithError(err).Errorf("Failed to create browser for URL: %s", task.URL)
+			return results
+		}
+
+		result := c.processTask(ctx, task)
+		c.stopper.Record(result)
+		if result.Success {
+			c.limiter.Success(host, task.Username)
+		} else if result.Code.Submitted() {
+			c.limiter.Failure(host, task.Username)
+		}
+		results = append(results, result)
+		if c.onResult != nil {
+			c.onResult(result)
+		}
+
+		// 被中断、登录页打不开或遇到锁定的尝试不记录，续跑时重新执行
+		if c.checkpoint != nil && ctx.Err() == nil && result.Lockout == "" && result.Code != OutcomeNavigationFailed {
+			if err := c.checkpoint.MarkDone(result); err != nil {
+				log.WithError(err).Error("Failed to save checkpoint")
 			}
+		}
 
-			if result.Error != nil && !strings.Contains(result.Error.Error(), "timed out") {
-				log.WithFields(log.Fields{
-					"url":      _task.URL,
-					"username": _task.Username,
-					"password": _task.Password,
-					"status":   "fail",
-					"error":    result.Error.Error(),
-				}).Debug("Login attempt failed")
+		switch {
+		case result.Success:
+			log.WithFields(log.Fields{
+				"url":      task.URL,
+				"username": task.Username,
+				"password": task.Password,
+				"status":   "success",
+			}).Info("Login successful")
+			return results
+		case result.Lockout != "":
+			// 暂停时等待暂停结束后重试本次尝试，其他处理方式放弃
+			if c.guard.Trip(host, task.Username, result.Lockout) != lockout.ActionPause {
+				return results
 			}
+			continue
+		case result.Code != OutcomeTimeout:
+			log.WithFields(log.Fields{
+				"url":      task.URL,
+				"username": task.Username,
+				"password": task.Password,
+				"status":   "fail",
+				"code":     result.Code,
+				"error":    result.Message,
+			}).Debug("Login attempt failed")
+		}
 
-			time.Sleep(c.delay)
-		}
-	}
-	return results
-}
-
-//
-//func (c *Cracker) Start(ctx context.Context, tasks []Task) []Result {
-//	var passwords []string
-//	if len(tasks) > 0 && len(tasks[0].Password) > 0 {
-//		passwords = []string{tasks[0].Password}
-//	} else {
-//		passwords = []string{
-//			"123456", "1q2w3e4r", "12345", "Aa123456", "admin",
-//			"admin123", "admin@123", "Admin@123",
-//		}
-//	}
-//
-//	log.WithFields(log.Fields{
-//		"action": "start_password_tests",
-//		"count":  len(passwords),
-//		"target": tasks[0].URL,
-//	}).Info("Starting password tests")
-//
-//	var results []Result
-//	for _, pass := range passwords {
-//		select {
-//		case <-ctx.Done():
-//			return results
-//		default:
-//			task := Task{
-//				URL:      tasks[0].URL,
-//				Username: tasks[0].Username,
-//				Password: pass,
-//			}
-//
-//			log.WithFields(log.Fields{
-//				"username": task.Username,
-//				"password": task.Password,
-//				"status":   "attempt",
-//			}).Debug("Testing credentials")
-//
-//			result := c.processTask(task)
-//			results = append(results, result)
-//
-//			if result.Success {
-//				log.WithFields(log.Fields{
-//					"url":    task.URL,
-//					"status": "success",
-//				}).Info("Login successful")
-//				return results
-//			}
-//
-//			if result.Error != nil && !strings.Contains(result.Error.Error(), "timed out") {
-//				log.WithFields(log.Fields{
-//					"error": result.Error.Error(),
-//				}).Debug("Login attempt failed")
-//			}
-//
-//			time.Sleep(c.delay)
-//		}
-//	}
-//	return results
-//}
+		// 间隔等待，可被取消
+		select {
+		case <-ctx.Done():
+		case <-time.After(c.delay):
+		}
+		return results
+	}
+}
 
-func (c *Cracker) processTask(ctx context.Context, task Task) Result {
-	result := Result{Task: task}
+// finish
+// @Description: 填写结果的分类、说明和错误
+// @receiver c
+// @param ctx
+// @param result
+// @param err
+func (c *Cracker) finish(ctx context.Context, result *Result, err error) {
+	result.Success = err == nil
+	result.Code, result.Message, result.Error = OutcomeSuccess, OutcomeSuccess.Message(), nil
+	if err == nil {
+		return
+	}
 
-	// 登录上下文
-	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	// 登录上下文可能已经超时，读取页面文本单独给一个短超时
+	textCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Second)
 	defer cancel()
 
-	errChan := make(chan error, 1)
-	doneChan := make(chan bool, 1)
+	text := c.browser.PageText(textCtx)
+	classified := Classify(err, text)
 
-	go func() {
-		defer close(errChan)
-		defer close(doneChan)
+	// 锁定检测：提示短语、429/423、突然出现的验证码
+	if c.guard != nil {
+		reason, fired := c.guard.Check(lockout.Observation{
+			Text:            text,
+			Status:          c.browser.ResponseStatus(),
+			CaptchaAppeared: !c.captchaSeen && c.browser.HasCaptcha(textCtx),
+		})
+		if fired {
+			result.Lockout = reason
+			classified = &Error{Code: OutcomeAccountLocked, Err: fmt.Errorf("%w (%s)", err, reason)}
+		}
+	}
+	result.Code, result.Message, result.Error = classified.Code, classified.Error(), classified
+}
 
-		// 密码处理
-		password := ProcessPassword(task.Password, task.Username)
+// isTransient
+// @Description: 判断是否为可重试的临时错误（页面没加载好、元素没出来、验证码识别失败等），
+// 密码错误或等待登录结果超时不属于临时错误
+// @param err
+// @return bool
+func isTransient(err error) bool {
+	return errors.Is(err, browser.ErrNavigationFailed) ||
+		errors.Is(err, browser.ErrElementNotFound) ||
+		errors.Is(err, browser.ErrCaptchaFailed)
+}
 
-		// 登录网站
-		if err := c.browser.Login(ctx, c.selector, task.Username, password); err != nil {
-			errChan <- fmt.Errorf("login failed: %w", err)
-			return
-		}
+func (c *Cracker) processTask(ctx context.Context, task Task) Result {
+	result := Result{Task: task, Limits: c.Limits()}
+
+	for attempt := 1; ; attempt++ {
+		result.Attempts = attempt
 
-		// 轮询结果
-		ticker := time.NewTicker(200 * time.Millisecond)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ctx.Done():
-				errChan <- fmt.Errorf("login verification timed out")
-				return
-			case <-ticker.C:
-				if c.browser.IsLoggedIn() {
-					doneChan <- true
-					return
-				}
+		// 每次尝试前打开登录页，避免在等待期间过期的页面上提交
+		err := c.load(ctx, task.URL)
+		if err == nil {
+			err = c.attempt(ctx, task)
+			result.Matched = c.browser.MatchedSelectors()
+			verdict := c.browser.LastVerdict()
+			result.Rule = verdict.Rule
+			result.Similarity = verdict.Similarity
+			if err == nil {
+				c.finish(ctx, &result, nil)
+				result.Tokens = c.browser.CapturedTokens()
+				return result
 			}
 		}
+
+		if attempt >= result.Limits.MaxAttempts || !isTransient(err) || ctx.Err() != nil {
+			c.finish(ctx, &result, err)
+			return result
+		}
+
+		log.WithFields(log.Fields{
+			"url":      task.URL,
+			"username": task.Username,
+			"attempt":  attempt,
+			"error":    err.Error(),
+		}).Debug("Transient error, retrying login attempt")
+	}
+}
+
+// attempt
+// @Description: 执行一次登录并等待结果
+// @receiver c
+// @param ctx
+// @param task
+// @return error 为 nil 表示登录成功
+func (c *Cracker) attempt(parent context.Context, task Task) error {
+	// 登录上下文
+	ctx, cancel := context.WithTimeout(parent, c.timeouts.Login)
+	defer cancel()
+
+	done := make(chan error, 1)
+
+	go func() {
+		// 登录网站，Login 会按判定规则等待登录结果。密码中的占位符已由 CreateTasks 替换
+		if err := c.browser.Login(ctx, c.selector, task.Username, task.Password); err != nil {
+			done <- fmt.Errorf("login failed: %w", err)
+			return
+		}
+		done <- nil
 	}()
 
 	select {
-	case err := <-errChan:
-		result.Error = err
-		result.Success = false
-		result.Attempts = 1
-	case <-doneChan:
-		result.Success = true
-		result.Attempts = 1
+	case err := <-done:
+		return err
 	case <-ctx.Done():
-		result.Error = fmt.Errorf("login attempt timed out after 15 seconds")
-		result.Success = false
-		result.Attempts = 1
+		if parent.Err() != nil {
+			return parent.Err()
+		}
+		return fmt.Errorf("%w after %v", browser.ErrLoginTimeout, c.timeouts.Login)
 	}
-
-	return result
 }
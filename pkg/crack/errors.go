@@ -0,0 +1,195 @@
+package crack
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"xiaoyu/pkg/browser"
+	"xiaoyu/pkg/lockout"
+)
+
+// Outcome 登录尝试的结果分类，作为稳定的字符串编码输出
+type Outcome string
+
+const (
+	OutcomeSuccess          Outcome = "success"
+	OutcomeNavigationFailed Outcome = "navigation_failed"
+	OutcomeFormNotFound     Outcome = "form_not_found"
+	OutcomeElementMissing   Outcome = "element_missing"
+	OutcomeCaptchaFailed    Outcome = "captcha_failed"
+	OutcomeWrongCredentials Outcome = "wrong_credentials"
+	OutcomeAccountLocked    Outcome = "account_locked"
+	OutcomeMFARequired      Outcome = "mfa_required"
+	OutcomePasswordExpired  Outcome = "password_expired"
+	OutcomeTimeout          Outcome = "timeout"
+	OutcomeUnknown          Outcome = "unknown"
+)
+
+// 各分类对应的哨兵错误，可用 errors.Is 判断；页面相关的直接沿用 browser 包中的定义
+var (
+	ErrNavigationFailed = browser.ErrNavigationFailed
+	ErrFormNotFound     = browser.ErrFormNotFound
+	ErrElementMissing   = browser.ErrElementNotFound
+	ErrCaptchaFailed    = browser.ErrCaptchaFailed
+	ErrWrongCredentials = errors.New("wrong username or password")
+	ErrAccountLocked    = errors.New("account is locked")
+	ErrMFARequired      = errors.New("multi-factor authentication required")
+	ErrPasswordExpired  = errors.New("password expired or must be changed")
+	ErrTimeout          = errors.New("timed out waiting for the login result")
+	ErrUnknown          = errors.New("unknown failure")
+)
+
+var outcomeErrors = map[Outcome]error{
+	OutcomeNavigationFailed: ErrNavigationFailed,
+	OutcomeFormNotFound:     ErrFormNotFound,
+	OutcomeElementMissing:   ErrElementMissing,
+	OutcomeCaptchaFailed:    ErrCaptchaFailed,
+	OutcomeWrongCredentials: ErrWrongCredentials,
+	OutcomeAccountLocked:    ErrAccountLocked,
+	OutcomeMFARequired:      ErrMFARequired,
+	OutcomePasswordExpired:  ErrPasswordExpired,
+	OutcomeTimeout:          ErrTimeout,
+	OutcomeUnknown:          ErrUnknown,
+}
+
+// Err
+// @Description: 分类对应的哨兵错误，成功返回 nil
+// @receiver o
+// @return error
+func (o Outcome) Err() error {
+	return outcomeErrors[o]
+}
+
+// Message
+// @Description: 分类的说明
+// @receiver o
+// @return string
+func (o Outcome) Message() string {
+	if o == OutcomeSuccess {
+		return "login succeeded"
+	}
+	if err := o.Err(); err != nil {
+		return err.Error()
+	}
+	return ErrUnknown.Error()
+}
+
+// Submitted
+// @Description: 该分类是否表示凭证已提交到目标（计入账号的失败次数）
+// @receiver o
+// @return bool
+func (o Outcome) Submitted() bool {
+	switch o {
+	case OutcomeNavigationFailed, OutcomeFormNotFound, OutcomeElementMissing, OutcomeCaptchaFailed:
+		return false
+	}
+	return true
+}
+
+// Error
+// @Description: 带分类的登录失败，同时包装分类的哨兵错误和原始错误
+type Error struct {
+	Code Outcome
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil || e.Err == e.Code.Err() {
+		return e.Code.Message()
+	}
+	return e.Code.Message() + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() []error {
+	return []error{e.Code.Err(), e.Err}
+}
+
+// 页面提示中区分账号状态的关键字（小写），锁定提示使用 lockout 包的短语表
+var (
+	mfaPhrases = []string{
+		"two-factor", "2-step", "two-step", "2fa", "authenticator", "one-time password", "verification code sent",
+		"二次验证", "双因素", "动态口令", "短信验证码", "令牌验证",
+	}
+	expiredPhrases = []string{
+		"password expired", "password has expired", "change your password", "must change", "reset your password",
+		"密码已过期", "密码过期", "修改初始密码", "首次登录", "请修改密码",
+	}
+)
+
+// OutcomeOf
+// @Description: 按错误链判断分类，nil 视为成功
+// @param err
+// @return Outcome
+func OutcomeOf(err error) Outcome {
+	var typed *Error
+	switch {
+	case err == nil:
+		return OutcomeSuccess
+	case errors.As(err, &typed):
+		return typed.Code
+	case errors.Is(err, ErrNavigationFailed):
+		return OutcomeNavigationFailed
+	case errors.Is(err, ErrFormNotFound):
+		return OutcomeFormNotFound
+	case errors.Is(err, ErrElementMissing):
+		return OutcomeElementMissing
+	case errors.Is(err, ErrCaptchaFailed):
+		return OutcomeCaptchaFailed
+	case errors.Is(err, browser.ErrLoginRejected):
+		return OutcomeWrongCredentials
+	case errors.Is(err, browser.ErrLoginTimeout), errors.Is(err, context.DeadlineExceeded):
+		return OutcomeTimeout
+	}
+	for code, sentinel := range outcomeErrors {
+		if errors.Is(err, sentinel) {
+			return code
+		}
+	}
+	return OutcomeUnknown
+}
+
+// Classify
+// @Description: 为失败的尝试分类。被拒绝或超时的尝试再按页面提示细分为账号锁定、需要多因素认证、密码过期
+// @param err
+// @param pageText 登录后的页面文本（可包含登录接口的响应）
+// @return *Error 成功时返回 nil
+func Classify(err error, pageText string) *Error {
+	if err == nil {
+		return nil
+	}
+	var typed *Error
+	if errors.As(err, &typed) {
+		return typed
+	}
+
+	code := OutcomeOf(err)
+	if code == OutcomeWrongCredentials || code == OutcomeTimeout || code == OutcomeUnknown {
+		text := strings.ToLower(pageText)
+		switch {
+		case lockedOut(text):
+			code = OutcomeAccountLocked
+		case containsAny(text, mfaPhrases):
+			code = OutcomeMFARequired
+		case containsAny(text, expiredPhrases):
+			code = OutcomePasswordExpired
+		}
+	}
+	return &Error{Code: code, Err: err}
+}
+
+var defaultCatalog = lockout.DefaultCatalog()
+
+func lockedOut(text string) bool {
+	_, _, ok := defaultCatalog.Match(text)
+	return ok
+}
+
+func containsAny(text string, phrases []string) bool {
+	for _, phrase := range phrases {
+		if strings.Contains(text, phrase) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package crack
+
+import (
+	"fmt"
+	"time"
+)
+
+// Strategy 任务的执行顺序
+type Strategy string
+
+const (
+	StrategyUser  Strategy = "user"  // 按用户：一个用户的密码试完再试下一个用户
+	StrategySpray Strategy = "spray" // 密码喷洒：一个密码试完所有用户，等待一段时间后再试下一个密码
+)
+
+// AttemptEstimate 一次尝试（启动浏览器、打开登录页、提交并等待结果）的估计耗时，仅用于估算总时长
+const AttemptEstimate = 5 * time.Second
+
+// ParseStrategy
+// @Description: 解析执行顺序
+// @param s
+// @return Strategy
+// @return error
+func ParseStrategy(s string) (Strategy, error) {
+	switch Strategy(s) {
+	case StrategyUser, StrategySpray:
+		return Strategy(s), nil
+	}
+	return "", fmt.Errorf("invalid strategy %q, expected user or spray", s)
+}
+
+// Plan
+// @Description: 按执行顺序将任务分为若干轮，轮内的任务可以并发，轮与轮之间依次执行。
+// 按用户时只有一轮；密码喷洒时每个密码一轮，按密码首次出现的顺序排列
+// @param tasks
+// @param strategy
+// @return [][]Task
+func Plan(tasks []Task, strategy Strategy) [][]Task {
+	if len(tasks) == 0 {
+		return nil
+	}
+	if strategy != StrategySpray {
+		return [][]Task{tasks}
+	}
+
+	var rounds [][]Task
+	index := make(map[string]int)
+	for _, task := range tasks {
+		i, ok := index[task.Password]
+		if !ok {
+			i = len(rounds)
+			index[task.Password] = i
+			rounds = append(rounds, nil)
+		}
+		rounds[i] = append(rounds[i], task)
+	}
+	return rounds
+}
+
+// Pacing
+// @Description: 估算总时长用到的并发与等待设置
+type Pacing struct {
+	Concurrency int           // 单主机并发数
+	Attempt     time.Duration // 单次尝试耗时，0 时使用 AttemptEstimate
+	Delay       time.Duration // 失败后的间隔
+	Rate        int           // 每分钟最多尝试次数，0 表示不限速
+	Jitter      time.Duration // 最大随机抖动
+	SprayWait   time.Duration // 密码喷洒时两轮之间的等待
+}
+
+// Estimate
+// @Description: 估算按计划执行的总时长（不含冷却与锁定暂停）
+// @param rounds
+// @param p
+// @return time.Duration
+func Estimate(rounds [][]Task, p Pacing) time.Duration {
+	concurrency := p.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	attempt := p.Attempt
+	if attempt <= 0 {
+		attempt = AttemptEstimate
+	}
+	perAttempt := attempt + p.Delay + p.Jitter/2
+
+	var total time.Duration
+	for i, round := range rounds {
+		if i > 0 {
+			total += p.SprayWait
+		}
+		n := len(round)
+		d := time.Duration((n+concurrency-1)/concurrency) * perAttempt
+		if p.Rate > 0 {
+			if limited := time.Duration(n) * time.Minute / time.Duration(p.Rate); limited > d {
+				d = limited
+			}
+		}
+		total += d
+	}
+	return total
+}
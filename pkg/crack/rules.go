@@ -0,0 +1,337 @@
+package crack
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"xiaoyu/pkg/pool"
+)
+
+// Vars
+// @Description: 密码中占位符的取值
+type Vars struct {
+	User    string // %user%：用户名
+	Host    string // %host%：主机名（不含端口），如 mail.example.com
+	Domain  string // %domain%：主域名去掉后缀，如 example
+	Company string // %company%：目标清单中的 company 字段
+	Year    int    // %year%：当前年份
+}
+
+// VarsFor
+// @Description: 按目标和用户生成占位符取值
+// @param url
+// @param username
+// @param company
+// @return Vars
+func VarsFor(url, username, company string) Vars {
+	host := pool.HostOf(url)
+	if i := strings.LastIndex(host, ":"); i > 0 && !strings.HasSuffix(host, "]") {
+		host = host[:i]
+	}
+
+	domain := host
+	labels := strings.Split(host, ".")
+	if len(labels) >= 2 && !isIP(host) {
+		domain = labels[len(labels)-2]
+		// 形如 example.com.cn 的二级后缀
+		if len(labels) >= 3 && len(domain) <= 3 && len(labels[len(labels)-1]) == 2 {
+			domain = labels[len(labels)-3]
+		}
+	}
+
+	return Vars{
+		User:    username,
+		Host:    host,
+		Domain:  domain,
+		Company: company,
+		Year:    time.Now().Year(),
+	}
+}
+
+func isIP(host string) bool {
+	for _, r := range host {
+		if r != '.' && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Expand
+// @Description: 替换占位符，%User% 为首字母大写的用户名
+// @param s
+// @param v
+// @return string
+func Expand(s string, v Vars) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	return strings.NewReplacer(
+		"%user%", v.User,
+		"%User%", capitalize(v.User),
+		"%host%", v.Host,
+		"%domain%", v.Domain,
+		"%company%", v.Company,
+		"%year%", strconv.Itoa(v.Year),
+	).Replace(s)
+}
+
+// missing
+// @Description: s 中是否引用了取值为空的占位符
+// @receiver v
+// @param s
+// @return bool
+func (v Vars) missing(s string) bool {
+	for placeholder, value := range map[string]string{
+		"%user%":    v.User,
+		"%User%":    v.User,
+		"%host%":    v.Host,
+		"%domain%":  v.Domain,
+		"%company%": v.Company,
+	} {
+		if value == "" && strings.Contains(s, placeholder) {
+			return true
+		}
+	}
+	return false
+}
+
+// op 规则中的一个操作，输入一个候选，输出零到多个候选
+type op func(word string, v Vars) []string
+
+// Rule
+// @Description: 一条规则，由若干操作依次组成
+type Rule struct {
+	Text string
+	ops  []op
+}
+
+// leetTable 常见的字符替换
+var leetTable = strings.NewReplacer("a", "@", "A", "@", "e", "3", "E", "3", "i", "1", "I", "1", "o", "0", "O", "0", "s", "$", "S", "$", "t", "7", "T", "7")
+
+// ParseRule
+// @Description: 解析一条规则，操作之间用空格分隔：
+// :（原样）、lower、upper、capitalize、toggle（大小写互换）、leet、reverse、
+// append:a,b（逐个追加后缀）、prepend:a,b（逐个添加前缀）、minlen:N、maxlen:N（按长度过滤）。
+// 后缀和前缀中可以使用占位符
+// @param text
+// @return Rule
+// @return error
+func ParseRule(text string) (Rule, error) {
+	rule := Rule{Text: text}
+	for _, field := range strings.Fields(text) {
+		if field == ":" {
+			rule.ops = append(rule.ops, func(word string, _ Vars) []string { return []string{word} })
+			continue
+		}
+
+		name, arg, hasArg := strings.Cut(field, ":")
+		switch name {
+		case "append", "prepend", "minlen", "maxlen":
+		default:
+			if hasArg {
+				return Rule{}, fmt.Errorf("rule %q: %s takes no argument", text, name)
+			}
+		}
+
+		var o op
+		switch name {
+		case "lower":
+			o = each(strings.ToLower)
+		case "upper":
+			o = each(strings.ToUpper)
+		case "capitalize":
+			o = each(capitalize)
+		case "toggle":
+			o = each(toggle)
+		case "leet":
+			o = each(leetTable.Replace)
+		case "reverse":
+			o = each(reverse)
+		case "append", "prepend":
+			if arg == "" {
+				return Rule{}, fmt.Errorf("rule %q: %s needs a comma separated list", text, name)
+			}
+			list := strings.Split(arg, ",")
+			prefix := name == "prepend"
+			o = func(word string, v Vars) []string {
+				out := make([]string, 0, len(list))
+				for _, item := range list {
+					// 引用了未配置的占位符（如没有 company）时跳过
+					if v.missing(item) {
+						continue
+					}
+					item = Expand(item, v)
+					if prefix {
+						out = append(out, item+word)
+					} else {
+						out = append(out, word+item)
+					}
+				}
+				return out
+			}
+		case "minlen", "maxlen":
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 0 {
+				return Rule{}, fmt.Errorf("rule %q: %s needs a non-negative number", text, name)
+			}
+			atLeast := name == "minlen"
+			o = func(word string, _ Vars) []string {
+				length := len([]rune(word))
+				if (atLeast && length < n) || (!atLeast && length > n) {
+					return nil
+				}
+				return []string{word}
+			}
+		default:
+			return Rule{}, fmt.Errorf("rule %q: unknown operation %q", text, field)
+		}
+		rule.ops = append(rule.ops, o)
+	}
+	if len(rule.ops) == 0 {
+		return Rule{}, fmt.Errorf("empty rule")
+	}
+	return rule, nil
+}
+
+// ParseRules
+// @Description: 逐行解析规则，忽略空行和 # 开头的注释
+// @param r
+// @return []Rule
+// @return error
+func ParseRules(r io.Reader) ([]Rule, error) {
+	var rules []Rule
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		rule, err := ParseRule(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// LoadRules
+// @Description: 从文件加载规则
+// @param path
+// @return []Rule
+// @return error
+func LoadRules(path string) ([]Rule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+	defer file.Close()
+
+	rules, err := ParseRules(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rules file %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// Apply
+// @Description: 对一个候选依次执行规则中的操作
+// @receiver r
+// @param word
+// @param v
+// @return []string
+func (r Rule) Apply(word string, v Vars) []string {
+	words := []string{word}
+	for _, o := range r.ops {
+		var next []string
+		for _, w := range words {
+			next = append(next, o(w, v)...)
+		}
+		words = next
+	}
+	return words
+}
+
+// Mutate
+// @Description: 替换占位符后按规则生成候选密码，按出现顺序去重。没有规则时只替换占位符，
+// 引用了取值为空的占位符的条目跳过
+// @param words
+// @param rules
+// @param v
+// @return []string
+func Mutate(words []string, rules []Rule, v Vars) []string {
+	seen := make(map[string]bool)
+	var out []string
+	add := func(candidate string) {
+		if candidate == "" || seen[candidate] {
+			return
+		}
+		seen[candidate] = true
+		out = append(out, candidate)
+	}
+
+	for _, word := range words {
+		// 空行和引用了未配置占位符的条目不参与变换，避免只剩后缀或前缀的候选
+		if word == "" || v.missing(word) {
+			continue
+		}
+		word = Expand(word, v)
+		if len(rules) == 0 {
+			add(word)
+			continue
+		}
+		for _, rule := range rules {
+			for _, candidate := range rule.Apply(word, v) {
+				add(candidate)
+			}
+		}
+	}
+	return out
+}
+
+func each(fn func(string) string) op {
+	return func(word string, _ Vars) []string {
+		return []string{fn(word)}
+	}
+}
+
+func capitalize(s string) string {
+	runes := []rune(s)
+	if len(runes) == 0 {
+		return s
+	}
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+func toggle(s string) string {
+	runes := []rune(s)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			runes[i] = unicode.ToLower(r)
+		} else {
+			runes[i] = unicode.ToUpper(r)
+		}
+	}
+	return string(runes)
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
@@ -0,0 +1,285 @@
+package crack
+
+import (
+	"fmt"
+	"sync"
+)
+
+// StopOn 登录成功后停止的范围
+type StopOn string
+
+const (
+	StopOnTarget StopOn = "target" // 停止该目标的全部用户
+	StopOnUser   StopOn = "user"   // 只停止该用户
+	StopOnNever  StopOn = "never"  // 不停止
+)
+
+// ParseStopOn
+// @Description: 解析成功后停止的范围
+// @param s
+// @return StopOn
+// @return error
+func ParseStopOn(s string) (StopOn, error) {
+	switch StopOn(s) {
+	case StopOnTarget, StopOnUser, StopOnNever:
+		return StopOn(s), nil
+	}
+	return "", fmt.Errorf("invalid stop-on %q, expected target, user or never", s)
+}
+
+// 停止原因，作为稳定的字符串编码输出
+const (
+	StopCompleted       = "completed"          // 计划内的尝试已全部完成
+	StopInterrupted     = "interrupted"        // 被中断
+	StopSuccess         = "success"            // 登录成功
+	StopMaxCrackNum     = "max_crack_num"      // 达到目标的最大尝试次数
+	StopMaxUserCrackNum = "max_user_crack_num" // 达到用户的最大尝试次数
+	StopMaxSuccess      = "max_success"        // 达到全局成功次数上限
+)
+
+// 停止的范围
+const (
+	ScopeGlobal = "global"
+	ScopeTarget = "target"
+	ScopeUser   = "user"
+)
+
+// StopPolicy
+// @Description: 停止条件，数量为 0 表示不限制
+type StopPolicy struct {
+	MaxPerTarget int    // 每个目标最多尝试次数
+	MaxPerUser   int    // 每个目标上每个用户最多尝试次数
+	On           StopOn // 登录成功后停止的范围
+	MaxSuccesses int    // 全部目标合计的成功次数上限
+}
+
+// Stop
+// @Description: 一次停止事件
+type Stop struct {
+	Scope     string `json:"scope"`
+	URL       string `json:"url"`
+	Username  string `json:"username,omitempty"`
+	Reason    string `json:"reason"`
+	Attempts  int    `json:"attempts"`
+	Successes int    `json:"successes"`
+}
+
+// Stopper
+// @Description: 在所有任务间共享的停止条件计数，尝试前预占次数，结束后记录结果
+type Stopper struct {
+	policy StopPolicy
+	onStop func(Stop)
+
+	mu        sync.Mutex
+	targets   map[string]*counter
+	users     map[string]*counter
+	successes int
+	global    string // 全局停止的原因
+}
+
+type counter struct {
+	attempts  int
+	successes int
+	reason    string // 停止原因，为空表示未停止
+}
+
+// NewStopper
+// @Description: 初始化停止条件
+// @param policy
+// @param onStop 目标、用户或全局停止时调用，每个范围只调用一次
+// @return *Stopper
+func NewStopper(policy StopPolicy, onStop func(Stop)) *Stopper {
+	return &Stopper{
+		policy:  policy,
+		onStop:  onStop,
+		targets: make(map[string]*counter),
+		users:   make(map[string]*counter),
+	}
+}
+
+func (s *Stopper) target(url string) *counter {
+	c, ok := s.targets[url]
+	if !ok {
+		c = &counter{}
+		s.targets[url] = c
+	}
+	return c
+}
+
+func (s *Stopper) user(url, username string) *counter {
+	key := url + "\x00" + username
+	c, ok := s.users[key]
+	if !ok {
+		c = &counter{}
+		s.users[key] = c
+	}
+	return c
+}
+
+// Stopped
+// @Description: 该任务所属的范围是否已停止，不预占次数
+// @receiver s
+// @param task
+// @return reason
+// @return stopped
+func (s *Stopper) Stopped(task Task) (reason string, stopped bool) {
+	if s == nil {
+		return "", false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stopped(task)
+}
+
+func (s *Stopper) stopped(task Task) (string, bool) {
+	if s.global != "" {
+		return s.global, true
+	}
+	if reason := s.target(task.URL).reason; reason != "" {
+		return reason, true
+	}
+	if reason := s.user(task.URL, task.Username).reason; reason != "" {
+		return reason, true
+	}
+	return "", false
+}
+
+// Allow
+// @Description: 判断是否可以继续尝试，可以时预占一次目标和用户的尝试次数
+// @receiver s
+// @param task
+// @return reason 不能继续时的停止原因
+// @return ok
+func (s *Stopper) Allow(task Task) (reason string, ok bool) {
+	if s == nil {
+		return "", true
+	}
+
+	var events []Stop
+	defer func() { s.emit(events) }()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if reason, stopped := s.stopped(task); stopped {
+		return reason, false
+	}
+
+	target := s.target(task.URL)
+	user := s.user(task.URL, task.Username)
+	if s.policy.MaxPerTarget > 0 && target.attempts >= s.policy.MaxPerTarget {
+		target.reason = StopMaxCrackNum
+		events = append(events, s.event(ScopeTarget, task, target))
+		return target.reason, false
+	}
+	if s.policy.MaxPerUser > 0 && user.attempts >= s.policy.MaxPerUser {
+		user.reason = StopMaxUserCrackNum
+		events = append(events, s.event(ScopeUser, task, user))
+		return user.reason, false
+	}
+
+	target.attempts++
+	user.attempts++
+	return "", true
+}
+
+// Record
+// @Description: 记录一次尝试的结果，成功时按策略停止用户、目标或全部
+// @receiver s
+// @param result
+func (s *Stopper) Record(result Result) {
+	if s == nil || !result.Success {
+		return
+	}
+
+	var events []Stop
+	defer func() { s.emit(events) }()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task := result.Task
+	target := s.target(task.URL)
+	user := s.user(task.URL, task.Username)
+	target.successes++
+	user.successes++
+	s.successes++
+
+	switch {
+	case s.policy.On == StopOnTarget && target.reason == "":
+		target.reason = StopSuccess
+		events = append(events, s.event(ScopeTarget, task, target))
+	case s.policy.On == StopOnUser && user.reason == "":
+		user.reason = StopSuccess
+		events = append(events, s.event(ScopeUser, task, user))
+	}
+
+	if s.policy.MaxSuccesses > 0 && s.successes >= s.policy.MaxSuccesses && s.global == "" {
+		s.global = StopMaxSuccess
+		events = append(events, Stop{
+			Scope:     ScopeGlobal,
+			URL:       task.URL,
+			Reason:    s.global,
+			Successes: s.successes,
+		})
+	}
+}
+
+// Finish
+// @Description: 目标的计划执行结束，未因停止条件停止的目标按是否被中断记录原因
+// @receiver s
+// @param url
+// @param interrupted
+// @return Stop 该目标最终的停止原因
+func (s *Stopper) Finish(url string, interrupted bool) Stop {
+	if s == nil {
+		return Stop{Scope: ScopeTarget, URL: url, Reason: StopCompleted}
+	}
+
+	var events []Stop
+	defer func() { s.emit(events) }()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	target := s.target(url)
+	task := Task{URL: url}
+	switch {
+	case target.reason != "":
+		// 停止时已经通知过
+		return s.event(ScopeTarget, task, target)
+	case s.global != "":
+		target.reason = s.global
+	case interrupted:
+		target.reason = StopInterrupted
+	default:
+		target.reason = StopCompleted
+	}
+	stop := s.event(ScopeTarget, task, target)
+	events = append(events, stop)
+	return stop
+}
+
+func (s *Stopper) event(scope string, task Task, c *counter) Stop {
+	stop := Stop{
+		Scope:     scope,
+		URL:       task.URL,
+		Reason:    c.reason,
+		Attempts:  c.attempts,
+		Successes: c.successes,
+	}
+	if scope == ScopeUser {
+		stop.Username = task.Username
+	}
+	return stop
+}
+
+func (s *Stopper) emit(events []Stop) {
+	if s.onStop == nil {
+		return
+	}
+	for _, stop := range events {
+		s.onStop(stop)
+	}
+}
@@ -0,0 +1,275 @@
+package creds
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Stdin 作为文件名时表示从标准输入读取
+const Stdin = "-"
+
+// ErrMissingColumn CSV 缺少用户名或密码列
+var ErrMissingColumn = errors.New("missing column")
+
+// CSV 表头中可识别的列名（小写）
+var (
+	userColumns = []string{"username", "user", "login", "account", "email", "用户名", "账号"}
+	passColumns = []string{"password", "pass", "passwd", "pwd", "密码"}
+)
+
+// Pair
+// @Description: 一组用户名和密码
+type Pair struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Stats
+// @Description: 读取凭证时的统计
+type Stats struct {
+	Source     string `json:"source"`
+	Lines      int    `json:"lines"`      // 读取的行数（CSV 不含表头）
+	Kept       int    `json:"kept"`       // 保留的条目数
+	Blank      int    `json:"blank"`      // 空行
+	Comments   int    `json:"comments"`   // # 开头的注释
+	Duplicates int    `json:"duplicates"` // 重复条目
+	Invalid    int    `json:"invalid"`    // 格式错误的行
+}
+
+// Open
+// @Description: 打开凭证文件，"-" 表示标准输入
+// @param path
+// @return io.ReadCloser
+// @return error
+func Open(path string) (io.ReadCloser, error) {
+	if path == Stdin {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+// clean
+// @Description: 去掉首尾空白（含 Windows 换行的 \r）和 UTF-8 BOM
+// @param s
+// @return string
+func clean(s string) string {
+	return strings.TrimSpace(strings.TrimPrefix(s, "\ufeff"))
+}
+
+// ReadList
+// @Description: 逐行读取用户名或密码列表：去掉首尾空白，跳过空行和 # 开头的注释，按出现顺序去重
+// @param r
+// @return []string
+// @return Stats
+// @return error
+func ReadList(r io.Reader) ([]string, Stats, error) {
+	var list []string
+	var stats Stats
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		stats.Lines++
+		line := clean(scanner.Text())
+		switch {
+		case line == "":
+			stats.Blank++
+		case strings.HasPrefix(line, "#"):
+			stats.Comments++
+		case seen[line]:
+			stats.Duplicates++
+		default:
+			seen[line] = true
+			list = append(list, line)
+		}
+	}
+	stats.Kept = len(list)
+	return list, stats, scanner.Err()
+}
+
+// ReadCombo
+// @Description: 逐行读取 user:pass 组合，按第一个冒号分割（密码中可以包含冒号），
+// 去掉首尾空白，跳过空行和注释，缺少冒号、用户名或密码为空的行计为格式错误
+// @param r
+// @return []Pair
+// @return Stats
+// @return error
+func ReadCombo(r io.Reader) ([]Pair, Stats, error) {
+	var pairs []Pair
+	var stats Stats
+	seen := make(map[Pair]bool)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		stats.Lines++
+		line := clean(scanner.Text())
+		switch {
+		case line == "":
+			stats.Blank++
+			continue
+		case strings.HasPrefix(line, "#"):
+			stats.Comments++
+			continue
+		}
+
+		user, pass, ok := strings.Cut(line, ":")
+		pair := Pair{Username: clean(user), Password: clean(pass)}
+		switch {
+		// 空密码无法作为候选，这里视为格式错误
+		case !ok || pair.Username == "" || pair.Password == "":
+			stats.Invalid++
+		case seen[pair]:
+			stats.Duplicates++
+		default:
+			seen[pair] = true
+			pairs = append(pairs, pair)
+		}
+	}
+	stats.Kept = len(pairs)
+	return pairs, stats, scanner.Err()
+}
+
+// ReadCSV
+// @Description: 读取带表头的 CSV，按列名识别用户名和密码列（不区分大小写），其余列忽略；
+// 空行与 # 开头的行由 CSV 解析器直接跳过，不计入统计
+// @param r
+// @return []Pair
+// @return Stats
+// @return error
+func ReadCSV(r io.Reader) ([]Pair, Stats, error) {
+	var stats Stats
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, stats, nil
+		}
+		return nil, stats, fmt.Errorf("failed to read csv header: %w", err)
+	}
+	userCol, passCol := column(header, userColumns), column(header, passColumns)
+	if userCol < 0 {
+		return nil, stats, fmt.Errorf("%w: no username column in csv header %q", ErrMissingColumn, header)
+	}
+	if passCol < 0 {
+		return nil, stats, fmt.Errorf("%w: no password column in csv header %q", ErrMissingColumn, header)
+	}
+
+	var pairs []Pair
+	seen := make(map[Pair]bool)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		stats.Lines++
+		if err != nil {
+			stats.Invalid++
+			continue
+		}
+		if userCol >= len(record) {
+			stats.Invalid++
+			continue
+		}
+
+		pair := Pair{Username: clean(record[userCol])}
+		if passCol < len(record) {
+			pair.Password = clean(record[passCol])
+		}
+		switch {
+		case pair.Username == "" || pair.Password == "":
+			stats.Invalid++
+		case seen[pair]:
+			stats.Duplicates++
+		default:
+			seen[pair] = true
+			pairs = append(pairs, pair)
+		}
+	}
+	stats.Kept = len(pairs)
+	return pairs, stats, nil
+}
+
+func column(header []string, names []string) int {
+	for i, h := range header {
+		h = strings.ToLower(clean(h))
+		for _, name := range names {
+			if h == name {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// LoadList
+// @Description: 从文件或标准输入读取列表
+// @param path
+// @return []string
+// @return Stats
+// @return error
+func LoadList(path string) ([]string, Stats, error) {
+	file, err := Open(path)
+	if err != nil {
+		return nil, Stats{Source: path}, err
+	}
+	defer file.Close()
+
+	list, stats, err := ReadList(file)
+	stats.Source = path
+	return list, stats, err
+}
+
+// LoadPairs
+// @Description: 从文件或标准输入读取凭证组合，.csv 文件按 CSV 解析，其余按 user:pass 解析
+// @param path
+// @return []Pair
+// @return Stats
+// @return error
+func LoadPairs(path string) ([]Pair, Stats, error) {
+	file, err := Open(path)
+	if err != nil {
+		return nil, Stats{Source: path}, err
+	}
+	defer file.Close()
+
+	var pairs []Pair
+	var stats Stats
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		pairs, stats, err = ReadCSV(file)
+	} else {
+		pairs, stats, err = ReadCombo(file)
+	}
+	stats.Source = path
+	return pairs, stats, err
+}
+
+// Dedupe
+// @Description: 去掉首尾空白和空项，按出现顺序去重，用于合并命令行与文件中的列表
+// @param list
+// @return []string
+func Dedupe(list []string) []string {
+	seen := make(map[string]bool, len(list))
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		item = clean(item)
+		if item == "" || seen[item] {
+			continue
+		}
+		seen[item] = true
+		out = append(out, item)
+	}
+	return out
+}
@@ -0,0 +1,157 @@
+package creds
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed defaults.yaml
+var defaultCredentials []byte
+
+// Profile
+// @Description: 一组默认凭证
+type Profile struct {
+	Users     []string `yaml:"users"`     // 未指定用户时使用的用户名
+	Pairs     []string `yaml:"pairs"`     // 厂商默认账号，user:pass
+	Passwords []string `yaml:"passwords"` // 对每个用户尝试的密码，可以使用占位符
+}
+
+// Defaults
+// @Description: 默认凭证库，products 按指纹识别出的产品名称匹配
+type Defaults struct {
+	Generic  Profile             `yaml:"generic"`
+	Products map[string]*Profile `yaml:"products"`
+}
+
+// BuiltinDefaults
+// @Description: 内置的默认凭证库
+// @return *Defaults
+func BuiltinDefaults() *Defaults {
+	d, err := parseDefaults(defaultCredentials)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in default credentials: %v", err))
+	}
+	return d
+}
+
+// LoadDefaults
+// @Description: 在内置默认凭证库的基础上追加文件中的凭证，文件格式与内置库相同，同名产品的条目追加在内置条目之后
+// @param path 为空时只使用内置库
+// @return *Defaults
+// @return error
+func LoadDefaults(path string) (*Defaults, error) {
+	d := BuiltinDefaults()
+	if path == "" {
+		return d, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read default credentials: %w", err)
+	}
+	extra, err := parseDefaults(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse default credentials %s: %w", path, err)
+	}
+
+	d.Generic.merge(&extra.Generic)
+	for name, p := range extra.Products {
+		if existing, ok := d.Products[name]; ok {
+			existing.merge(p)
+		} else {
+			d.Products[name] = p
+		}
+	}
+	return d, nil
+}
+
+func parseDefaults(data []byte) (*Defaults, error) {
+	d := &Defaults{}
+	if err := yaml.Unmarshal(data, d); err != nil {
+		return nil, err
+	}
+	if d.Products == nil {
+		d.Products = make(map[string]*Profile)
+	}
+
+	if err := d.Generic.validate(); err != nil {
+		return nil, fmt.Errorf("generic: %w", err)
+	}
+	for name, p := range d.Products {
+		if p == nil {
+			p = &Profile{}
+			d.Products[name] = p
+		}
+		if err := p.validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return d, nil
+}
+
+func (p *Profile) validate() error {
+	for _, raw := range p.Pairs {
+		if _, ok := parsePair(raw); !ok {
+			return fmt.Errorf("invalid pair %q, expected user:pass", raw)
+		}
+	}
+	return nil
+}
+
+func (p *Profile) merge(other *Profile) {
+	p.Users = append(p.Users, other.Users...)
+	p.Pairs = append(p.Pairs, other.Pairs...)
+	p.Passwords = append(p.Passwords, other.Passwords...)
+}
+
+// parsePair 按第一个冒号分割 user:pass，用户名和密码都不能为空
+func parsePair(raw string) (Pair, bool) {
+	user, pass, ok := strings.Cut(raw, ":")
+	pair := Pair{Username: clean(user), Password: clean(pass)}
+	return pair, ok && pair.Username != "" && pair.Password != ""
+}
+
+// Credentials
+// @Description: 按产品生成默认凭证，顺序为产品的默认账号，再对每个用户依次尝试产品和通用的密码，按出现顺序去重。
+// 密码中的占位符原样保留，由调用方按用户和目标替换
+// @receiver d
+// @param product 指纹识别出的产品名称，为空时只使用通用凭证
+// @param users 指定的用户，为空时使用产品和通用的默认用户
+// @return []Pair
+func (d *Defaults) Credentials(product string, users []string) []Pair {
+	profile := &Profile{}
+	if p, ok := d.Products[product]; ok && product != "" {
+		profile = p
+	}
+
+	var out []Pair
+	seen := make(map[Pair]bool)
+	add := func(pair Pair) {
+		if seen[pair] {
+			return
+		}
+		seen[pair] = true
+		out = append(out, pair)
+	}
+
+	for _, raw := range profile.Pairs {
+		if pair, ok := parsePair(raw); ok {
+			add(pair)
+		}
+	}
+
+	if len(users) == 0 {
+		users = Dedupe(append(append([]string{}, profile.Users...), d.Generic.Users...))
+	}
+	passwords := Dedupe(append(append([]string{}, profile.Passwords...), d.Generic.Passwords...))
+	for _, user := range users {
+		for _, pass := range passwords {
+			add(Pair{Username: user, Password: pass})
+		}
+	}
+	return out
+}
@@ -0,0 +1,370 @@
+package fingerprint
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"xiaoyu/pkg/browser"
+)
+
+// Strings 既可以写成单个字符串也可以写成列表
+type Strings []string
+
+func (s *Strings) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		if node.Value != "" {
+			*s = Strings{node.Value}
+		}
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
+}
+
+// Product
+// @Description: 一个产品的指纹与登录页选择器，字段与 config.yaml 中 framework 列表一致
+type Product struct {
+	Name string `yaml:"name"`
+
+	// 登录页选择器
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	Captcha      string `yaml:"captcha"`
+	CaptchaImage string `yaml:"captchaImage"`
+	Button       string `yaml:"button"`
+
+	// 指纹特征，命中任意一项即视为候选，命中越多越优先
+	Indicate Strings           `yaml:"indicate"` // HTML 片段
+	HTML     Strings           `yaml:"html"`     // HTML 片段，与 indicate 相同
+	Title    Strings           `yaml:"title"`    // 标题包含的文本（不区分大小写）
+	Favicon  []int32           `yaml:"favicon"`  // 图标哈希，与 Shodan http.favicon.hash 一致
+	Headers  map[string]string `yaml:"headers"`  // 响应头名称 -> 包含的文本（不区分大小写）
+	Scripts  Strings           `yaml:"scripts"`  // 脚本地址包含的文本
+}
+
+// Selector
+// @Description: 产品的登录页选择器
+// @receiver p
+// @return *browser.Selector
+func (p *Product) Selector() *browser.Selector {
+	return &browser.Selector{
+		UserInput:     p.Username,
+		PasswordInput: p.Password,
+		LoginBtn:      p.Button,
+		CaptchaInput:  p.Captcha,
+		CaptchaImg:    p.CaptchaImage,
+	}
+}
+
+// Match
+// @Description: 一次命中的产品与证据
+type Match struct {
+	Product  *Product `json:"-"`
+	Name     string   `json:"product"`
+	Evidence []string `json:"evidence"`
+}
+
+// DB
+// @Description: 产品指纹库，同名产品后加载的覆盖先加载的
+type DB struct {
+	Products []*Product
+}
+
+// document 指纹文件既可以是带 framework 列表的 config.yaml，也可以是顶层列表或单个产品
+type document struct {
+	Framework []*Product `yaml:"framework"`
+}
+
+// Parse
+// @Description: 解析指纹文件
+// @param data
+// @return []*Product
+// @return error
+func Parse(data []byte) ([]*Product, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	if len(node.Content) == 0 {
+		return nil, nil
+	}
+
+	var products []*Product
+	root := node.Content[0]
+	switch {
+	case root.Kind == yaml.SequenceNode:
+		if err := root.Decode(&products); err != nil {
+			return nil, err
+		}
+	case root.Kind == yaml.MappingNode && hasKey(root, "framework"):
+		var doc document
+		if err := root.Decode(&doc); err != nil {
+			return nil, err
+		}
+		products = doc.Framework
+	case root.Kind == yaml.MappingNode:
+		var p Product
+		if err := root.Decode(&p); err != nil {
+			return nil, err
+		}
+		products = []*Product{&p}
+	default:
+		return nil, fmt.Errorf("unexpected yaml %s", root.Tag)
+	}
+
+	for i, p := range products {
+		if p == nil || strings.TrimSpace(p.Name) == "" {
+			return nil, fmt.Errorf("product %d has no name", i+1)
+		}
+	}
+	return products, nil
+}
+
+func hasKey(node *yaml.Node, key string) bool {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Add
+// @Description: 加入产品，同名产品覆盖原有定义
+// @receiver db
+// @param products
+func (db *DB) Add(products ...*Product) {
+	for _, p := range products {
+		replaced := false
+		for i, existing := range db.Products {
+			if existing.Name == p.Name {
+				db.Products[i] = p
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			db.Products = append(db.Products, p)
+		}
+	}
+}
+
+// Load
+// @Description: 加载指纹文件，或目录下全部 .yaml/.yml 文件（按文件名排序）
+// @receiver db
+// @param path
+// @return error
+func (db *DB) Load(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read fingerprints: %w", err)
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		files = nil
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return fmt.Errorf("failed to read fingerprints: %w", err)
+		}
+		for _, entry := range entries {
+			ext := strings.ToLower(filepath.Ext(entry.Name()))
+			if !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+		sort.Strings(files)
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read fingerprints: %w", err)
+		}
+		products, err := Parse(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse fingerprints %s: %w", file, err)
+		}
+		db.Add(products...)
+	}
+	return nil
+}
+
+// NeedsFavicon
+// @Description: 是否有产品使用图标哈希，没有时无需请求图标
+// @receiver db
+// @return bool
+func (db *DB) NeedsFavicon() bool {
+	for _, p := range db.Products {
+		if len(p.Favicon) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Match
+// @Description: 按页面特征匹配产品，返回命中的产品，证据多的在前，相同时按定义顺序
+// @receiver db
+// @param f
+// @return []Match
+func (db *DB) Match(f *browser.PageFeatures) []Match {
+	var matches []Match
+	for _, p := range db.Products {
+		if evidence := p.evidence(f); len(evidence) > 0 {
+			matches = append(matches, Match{Product: p, Name: p.Name, Evidence: evidence})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return len(matches[i].Evidence) > len(matches[j].Evidence)
+	})
+	return matches
+}
+
+// evidence
+// @Description: 产品在页面上命中的特征
+// @receiver p
+// @param f
+// @return []string
+func (p *Product) evidence(f *browser.PageFeatures) []string {
+	var evidence []string
+
+	for _, marker := range append(append(Strings{}, p.Indicate...), p.HTML...) {
+		if marker != "" && strings.Contains(f.HTML, marker) {
+			evidence = append(evidence, "html:"+marker)
+		}
+	}
+
+	title := strings.ToLower(f.Title)
+	for _, t := range p.Title {
+		if t != "" && strings.Contains(title, strings.ToLower(t)) {
+			evidence = append(evidence, "title:"+t)
+		}
+	}
+
+	if len(p.Favicon) > 0 && len(f.Favicon) > 0 {
+		hash := FaviconHash(f.Favicon)
+		for _, h := range p.Favicon {
+			if h == hash {
+				evidence = append(evidence, "favicon:"+strconv.Itoa(int(h)))
+			}
+		}
+	}
+
+	// 按名称排序，保证证据顺序稳定
+	names := make([]string, 0, len(p.Headers))
+	for name := range p.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, ok := f.Headers[strings.ToLower(name)]
+		if ok && strings.Contains(strings.ToLower(value), strings.ToLower(p.Headers[name])) {
+			evidence = append(evidence, "header:"+name+"="+value)
+		}
+	}
+
+	for _, script := range p.Scripts {
+		for _, src := range f.Scripts {
+			if script != "" && strings.Contains(src, script) {
+				evidence = append(evidence, "script:"+src)
+				break
+			}
+		}
+	}
+	return evidence
+}
+
+// ParseDB
+// @Description: 由内置的指纹数据创建指纹库
+// @param data
+// @return *DB
+// @return error
+func ParseDB(data []byte) (*DB, error) {
+	db := &DB{}
+	products, err := Parse(bytes.TrimSpace(data))
+	if err != nil {
+		return nil, err
+	}
+	db.Add(products...)
+	return db, nil
+}
+
+// confirmTimeout 确认产品选择器时等待元素的最长时间
+const confirmTimeout = 2 * time.Second
+
+// Identify
+// @Description: 识别当前页面的产品，并确认该产品的用户名和密码选择器在页面上恰好匹配一个可见元素；
+// 特征相同的产品依次确认，全部不通过时返回 nil，由调用方退回启发式探测
+// @receiver db
+// @param ctx
+// @param b
+// @return *browser.Selector
+// @return *Match
+// @return error
+func (db *DB) Identify(ctx context.Context, b *browser.Browser) (*browser.Selector, *Match, error) {
+	if db == nil || len(db.Products) == 0 {
+		return nil, nil, nil
+	}
+
+	f, err := b.Features(ctx, db.NeedsFavicon())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, m := range db.Match(f) {
+		logger := log.WithFields(log.Fields{
+			"url":      f.URL,
+			"product":  m.Name,
+			"evidence": m.Evidence,
+		})
+
+		s := m.Product.Selector()
+		if s.UserInput == "" || s.PasswordInput == "" {
+			logger.Debug("Product has no login selectors")
+			continue
+		}
+
+		confirmCtx, cancel := context.WithTimeout(ctx, confirmTimeout)
+		reports := b.ValidateSelector(confirmCtx, &browser.Selector{UserInput: s.UserInput, PasswordInput: s.PasswordInput})
+		cancel()
+		if !passed(reports, browser.FieldUserInput, browser.FieldPasswordInput) {
+			logger.Debug("Product selectors not found on page")
+			continue
+		}
+
+		logger.Info("Product fingerprint matched")
+		m := m
+		return s, &m, nil
+	}
+	return nil, nil, nil
+}
+
+func passed(reports []browser.FieldReport, fields ...string) bool {
+	for _, field := range fields {
+		ok := false
+		for _, r := range reports {
+			if r.Field == field {
+				ok = r.OK
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
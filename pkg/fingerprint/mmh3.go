@@ -0,0 +1,78 @@
+package fingerprint
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"math/bits"
+	"strings"
+)
+
+// Murmur3
+// @Description: MurmurHash3 x86 32 位哈希（种子为 0），结果按有符号整数返回，与 Python mmh3.hash 一致
+// @param data
+// @return int32
+func Murmur3(data []byte) int32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+
+	var h uint32
+	n := len(data) / 4
+	for i := 0; i < n; i++ {
+		k := binary.LittleEndian.Uint32(data[i*4:])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+
+	var k uint32
+	tail := data[n*4:]
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return int32(h)
+}
+
+// FaviconHash
+// @Description: 图标哈希，与 Shodan 的 http.favicon.hash 算法一致：
+// 对按 76 字符换行的 base64（与 Python base64.encodebytes 相同）计算 Murmur3
+// @param data
+// @return int32
+func FaviconHash(data []byte) int32 {
+	if len(data) == 0 {
+		return Murmur3(nil)
+	}
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var sb strings.Builder
+	for len(encoded) > 76 {
+		sb.WriteString(encoded[:76])
+		sb.WriteByte('\n')
+		encoded = encoded[76:]
+	}
+	sb.WriteString(encoded)
+	sb.WriteByte('\n')
+	return Murmur3([]byte(sb.String()))
+}
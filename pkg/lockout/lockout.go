@@ -0,0 +1,325 @@
+package lockout
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed phrases.yaml
+var defaultPhrases []byte
+
+// Action 检测到锁定后的处理方式
+type Action string
+
+const (
+	ActionPause    Action = "pause"     // 暂停一段时间后继续，再次触发时放弃该用户
+	ActionSkipUser Action = "skip-user" // 放弃该主机上的该用户
+	ActionSkipHost Action = "skip-host" // 放弃该主机
+	ActionAbort    Action = "abort"     // 结束整个任务
+)
+
+// Actions 全部处理方式
+var Actions = []Action{ActionPause, ActionSkipUser, ActionSkipHost, ActionAbort}
+
+// ParseAction
+// @Description: 解析处理方式
+// @param s
+// @return Action
+// @return error
+func ParseAction(s string) (Action, error) {
+	for _, a := range Actions {
+		if string(a) == s {
+			return a, nil
+		}
+	}
+	return "", fmt.Errorf("invalid lockout action %q, expected one of pause, skip-user, skip-host, abort", s)
+}
+
+// Catalog
+// @Description: 按语言分组的锁定提示短语
+type Catalog struct {
+	Phrases map[string][]string
+}
+
+// DefaultCatalog
+// @Description: 内置的短语表
+// @return *Catalog
+func DefaultCatalog() *Catalog {
+	c, err := parseCatalog(defaultPhrases)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in lockout phrases: %v", err))
+	}
+	return c
+}
+
+// LoadCatalog
+// @Description: 在内置短语表的基础上追加文件中的短语，文件格式与内置表相同
+// @param path 为空时只使用内置短语表
+// @return *Catalog
+// @return error
+func LoadCatalog(path string) (*Catalog, error) {
+	c := DefaultCatalog()
+	if path == "" {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lockout phrases: %w", err)
+	}
+	extra, err := parseCatalog(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse lockout phrases %s: %w", path, err)
+	}
+	for lang, phrases := range extra.Phrases {
+		c.Phrases[lang] = append(c.Phrases[lang], phrases...)
+	}
+	return c, nil
+}
+
+func parseCatalog(data []byte) (*Catalog, error) {
+	phrases := make(map[string][]string)
+	if err := yaml.Unmarshal(data, &phrases); err != nil {
+		return nil, err
+	}
+	for lang, list := range phrases {
+		var cleaned []string
+		for _, phrase := range list {
+			if phrase = strings.ToLower(strings.TrimSpace(phrase)); phrase != "" {
+				cleaned = append(cleaned, phrase)
+			}
+		}
+		phrases[lang] = cleaned
+	}
+	return &Catalog{Phrases: phrases}, nil
+}
+
+// Match
+// @Description: 文本中命中的第一个短语
+// @receiver c
+// @param text
+// @return lang
+// @return phrase
+// @return ok
+func (c *Catalog) Match(text string) (lang string, phrase string, ok bool) {
+	text = strings.ToLower(text)
+
+	langs := make([]string, 0, len(c.Phrases))
+	for l := range c.Phrases {
+		langs = append(langs, l)
+	}
+	sort.Strings(langs)
+
+	for _, l := range langs {
+		for _, p := range c.Phrases[l] {
+			if strings.Contains(text, p) {
+				return l, p, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// Observation
+// @Description: 一次失败尝试后观察到的页面状态
+type Observation struct {
+	Text            string // 页面文本（可包含登录接口的响应）
+	Status          int    // 登录响应或当前文档的 HTTP 状态码
+	CaptchaAppeared bool   // 开始时没有验证码，现在出现了
+}
+
+// Detector
+// @Description: 锁定检测：提示短语、HTTP 429/423、突然出现的验证码
+type Detector struct {
+	catalog *Catalog
+}
+
+// NewDetector
+// @Description: 初始化锁定检测
+// @param c 为 nil 时使用内置短语表
+// @return *Detector
+func NewDetector(c *Catalog) *Detector {
+	if c == nil {
+		c = DefaultCatalog()
+	}
+	return &Detector{catalog: c}
+}
+
+// Check
+// @Description: 判断是否出现锁定信号
+// @receiver d
+// @param o
+// @return reason 触发原因
+// @return fired
+func (d *Detector) Check(o Observation) (reason string, fired bool) {
+	switch {
+	case o.Status == 429:
+		return "http status 429 too many requests", true
+	case o.Status == 423:
+		return "http status 423 locked", true
+	}
+	if lang, phrase, ok := d.catalog.Match(o.Text); ok {
+		return fmt.Sprintf("phrase %q (%s)", phrase, lang), true
+	}
+	if o.CaptchaAppeared {
+		return "captcha appeared after failed attempts", true
+	}
+	return "", false
+}
+
+// Guard
+// @Description: 在所有任务间共享的锁定检测与锁定状态，记录被放弃的用户和主机
+type Guard struct {
+	detector *Detector
+
+	mu     sync.Mutex
+	action Action
+	pause  time.Duration
+	abort  func(reason string)
+	users  map[string]string    // host + user -> 原因
+	hosts  map[string]string    // host -> 原因
+	until  map[string]time.Time // host + user -> 暂停截止时间，暂停过一次后保留
+}
+
+// NewGuard
+// @Description: 初始化锁定状态
+// @param detector 锁定检测
+// @param action 处理方式
+// @param pause 暂停时长
+// @param abort 处理方式为 abort 时调用，用于结束整个任务
+// @return *Guard
+func NewGuard(detector *Detector, action Action, pause time.Duration, abort func(reason string)) *Guard {
+	if detector == nil {
+		detector = NewDetector(nil)
+	}
+	return &Guard{
+		detector: detector,
+		action:   action,
+		pause:    pause,
+		abort:    abort,
+		users:    make(map[string]string),
+		hosts:    make(map[string]string),
+		until:    make(map[string]time.Time),
+	}
+}
+
+// Check
+// @Description: 判断是否出现锁定信号
+// @receiver g
+// @param o
+// @return reason
+// @return fired
+func (g *Guard) Check(o Observation) (reason string, fired bool) {
+	return g.detector.Check(o)
+}
+
+func userKey(host, user string) string {
+	return host + "\x00" + user
+}
+
+// Blocked
+// @Description: 该主机或该用户是否已被放弃
+// @receiver g
+// @param host
+// @param user
+// @return reason
+// @return blocked
+func (g *Guard) Blocked(host, user string) (reason string, blocked bool) {
+	if g == nil {
+		return "", false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if reason, ok := g.hosts[host]; ok {
+		return reason, true
+	}
+	reason, ok := g.users[userKey(host, user)]
+	return reason, ok
+}
+
+// Trip
+// @Description: 记录一次锁定并按处理方式生效，返回实际采取的处理方式。
+// 暂停期间其他并发尝试再次报告锁定时不延长暂停，暂停结束后仍然锁定则放弃该用户
+// @receiver g
+// @param host
+// @param user
+// @param reason
+// @return Action
+func (g *Guard) Trip(host, user, reason string) Action {
+	g.mu.Lock()
+
+	action := g.action
+	key := userKey(host, user)
+	now := time.Now()
+	if action == ActionPause {
+		if until, ok := g.until[key]; ok {
+			if now.Before(until) {
+				g.mu.Unlock()
+				return ActionPause
+			}
+			// 暂停后仍然锁定，放弃该用户
+			action = ActionSkipUser
+		}
+	}
+
+	switch action {
+	case ActionPause:
+		g.until[key] = now.Add(g.pause)
+	case ActionSkipUser:
+		g.users[key] = reason
+	case ActionSkipHost, ActionAbort:
+		g.hosts[host] = reason
+	}
+	g.mu.Unlock()
+
+	log.WithFields(log.Fields{
+		"host":     host,
+		"username": user,
+		"reason":   reason,
+		"action":   action,
+	}).Warn("Account lockout detected")
+
+	if action == ActionAbort && g.abort != nil {
+		g.abort(reason)
+	}
+	return action
+}
+
+// WaitUntilClear
+// @Description: 该用户处于锁定暂停期间时等待暂停结束，所有任务在尝试前调用，可被上下文取消
+// @receiver g
+// @param ctx
+// @param host
+// @param user
+// @return error
+func (g *Guard) WaitUntilClear(ctx context.Context, host, user string) error {
+	if g == nil {
+		return nil
+	}
+	for {
+		g.mu.Lock()
+		wait := time.Until(g.until[userKey(host, user)])
+		g.mu.Unlock()
+		if wait <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"xiaoyu/pkg/utils"
+)
+
+// 记录类型
+const (
+	TypeDetection = "detection"
+	TypeAttempt   = "attempt"
+	TypeSuccess   = "success"
+	TypeStop      = "stop"
+)
+
+// Record
+// @Description: JSONL 中的一条事件记录
+type Record struct {
+	Type string          `json:"type"`
+	Time time.Time       `json:"time"`
+	URL  string          `json:"url"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Report
+// @Description: 运行结束时合并生成的 JSON 文档
+type Report struct {
+	GeneratedAt time.Time `json:"generatedAt"`
+	Detections  []Record  `json:"detections"`
+	Attempts    []Record  `json:"attempts"`
+	Successes   []Record  `json:"successes"`
+	Stops       []Record  `json:"stops"`
+}
+
+// Writer
+// @Description: 流式结果写入器，每条记录追加写入 JSONL 并立即落盘，可被多个协程并发调用
+type Writer struct {
+	mu         sync.Mutex
+	file       *os.File
+	streamFile string
+	outputFile string
+}
+
+// StreamFileFor
+// @Description: 根据输出文件推导默认的 JSONL 文件名，如 output.json -> output.jsonl
+// @param outputFile
+// @return string
+func StreamFileFor(outputFile string) string {
+	return strings.TrimSuffix(outputFile, filepath.Ext(outputFile)) + ".jsonl"
+}
+
+// New
+// @Description: 打开 JSONL 文件，续跑时追加，否则清空上次运行的记录
+// @param outputFile 运行结束时写入的合并 JSON 文件
+// @param streamFile 流式 JSONL 文件，为空时由 outputFile 推导
+// @param resume 是否续跑
+// @return *Writer
+// @return error
+func New(outputFile string, streamFile string, resume bool) (*Writer, error) {
+	if streamFile == "" {
+		streamFile = StreamFileFor(outputFile)
+	}
+	if streamFile == outputFile {
+		return nil, fmt.Errorf("stream file and output file must differ: %s", outputFile)
+	}
+
+	flag := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if !resume {
+		flag |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(streamFile, flag, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stream file: %w", err)
+	}
+
+	if err = utils.TerminateLine(streamFile, file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to repair stream file: %w", err)
+	}
+
+	return &Writer{
+		file:       file,
+		streamFile: streamFile,
+		outputFile: outputFile,
+	}, nil
+}
+
+// Write
+// @Description: 写入一条记录并刷新到磁盘
+// @receiver w
+// @param kind 记录类型
+// @param url 目标URL
+// @param data 记录内容
+// @return error
+func (w *Writer) Write(kind string, url string, data interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s record: %w", kind, err)
+	}
+
+	line, err := json.Marshal(Record{
+		Type: kind,
+		Time: time.Now(),
+		URL:  url,
+		Data: raw,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s record: %w", kind, err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return fmt.Errorf("result writer is closed")
+	}
+	if _, err = w.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+	if err = w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to flush record: %w", err)
+	}
+	return nil
+}
+
+// Close
+// @Description: 关闭 JSONL 文件，并将其中的全部记录合并写入输出文件
+// @receiver w
+// @return error
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close stream file: %w", err)
+	}
+	w.file = nil
+
+	report, err := ReadReport(w.streamFile)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+
+	// 先写临时文件再替换，避免中途退出留下半个文件
+	tmp := w.outputFile + ".tmp"
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	if err = os.Rename(tmp, w.outputFile); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	return nil
+}
+
+// ReadReport
+// @Description: 读取 JSONL 文件并按记录类型合并，损坏的行（如进程被杀时的半行）会被跳过
+// @param streamFile
+// @return *Report
+// @return error
+func ReadReport(streamFile string) (*Report, error) {
+	file, err := os.Open(streamFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stream file: %w", err)
+	}
+	defer file.Close()
+
+	report := &Report{
+		GeneratedAt: time.Now(),
+		Detections:  []Record{},
+		Attempts:    []Record{},
+		Successes:   []Record{},
+		Stops:       []Record{},
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var r Record
+		if err = json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+		switch r.Type {
+		case TypeDetection:
+			report.Detections = append(report.Detections, r)
+		case TypeAttempt:
+			report.Attempts = append(report.Attempts, r)
+		case TypeSuccess:
+			report.Successes = append(report.Successes, r)
+		case TypeStop:
+			report.Stops = append(report.Stops, r)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stream file: %w", err)
+	}
+	return report, nil
+}
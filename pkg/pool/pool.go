@@ -0,0 +1,192 @@
+package pool
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Pool
+// @Description: 有界协程池，最多 threads 个 worker 按提交顺序从队列中取任务执行，同时限制单主机并发
+type Pool struct {
+	threads int
+	perHost int
+
+	mu      sync.Mutex
+	queue   []*job
+	workers int            // 正在运行的 worker 数
+	hosts   map[string]int // 各主机正在执行的任务数
+	wg      sync.WaitGroup
+}
+
+// job 排队中的任务
+type job struct {
+	ctx   context.Context
+	host  string
+	fn    func(ctx context.Context)
+	group *sync.WaitGroup
+}
+
+// New
+// @Description: 初始化协程池
+// @param threads 全局最大并发数
+// @param hostThreads 单主机最大并发数，<=0 表示与全局一致
+// @return *Pool
+func New(threads int, hostThreads int) *Pool {
+	if threads <= 0 {
+		threads = 1
+	}
+	if hostThreads <= 0 || hostThreads > threads {
+		hostThreads = threads
+	}
+	return &Pool{
+		threads: threads,
+		perHost: hostThreads,
+		hosts:   make(map[string]int),
+	}
+}
+
+// Submit
+// @Description: 提交任务，立即返回；任务按提交顺序执行，主机已满时先执行排在后面的其他主机的任务，
+// 上下文取消时尚未执行的任务会被丢弃。任务内部可以继续 Submit。
+// @receiver p
+// @param ctx
+// @param host
+// @param fn
+func (p *Pool) Submit(ctx context.Context, host string, fn func(ctx context.Context)) {
+	p.submit(ctx, host, fn, nil)
+}
+
+func (p *Pool) submit(ctx context.Context, host string, fn func(ctx context.Context), group *sync.WaitGroup) {
+	p.wg.Add(1)
+	if group != nil {
+		group.Add(1)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.queue = append(p.queue, &job{ctx: ctx, host: host, fn: fn, group: group})
+	// worker 在队列中没有可执行的任务时退出，按需补足
+	if p.workers < p.threads {
+		p.workers++
+		go p.work()
+	}
+}
+
+// work 循环执行队列中的任务，没有可执行的任务时退出
+func (p *Pool) work() {
+	for {
+		p.mu.Lock()
+		j := p.next()
+		if j == nil {
+			p.workers--
+			p.mu.Unlock()
+			return
+		}
+		p.mu.Unlock()
+
+		j.fn(j.ctx)
+
+		p.mu.Lock()
+		if p.hosts[j.host]--; p.hosts[j.host] <= 0 {
+			delete(p.hosts, j.host)
+		}
+		p.mu.Unlock()
+		p.done(j)
+	}
+}
+
+// next
+// @Description: 取出队列中最早的、所属主机未满的任务并占用主机槽位，丢弃上下文已取消的任务。调用方需持有锁
+// @receiver p
+// @return *job 没有可执行的任务时为 nil
+func (p *Pool) next() *job {
+	for i := 0; i < len(p.queue); {
+		j := p.queue[i]
+		if j.ctx.Err() != nil {
+			p.queue = append(p.queue[:i], p.queue[i+1:]...)
+			p.done(j)
+			continue
+		}
+		if p.hosts[j.host] < p.perHost {
+			p.queue = append(p.queue[:i], p.queue[i+1:]...)
+			p.hosts[j.host]++
+			return j
+		}
+		i++
+	}
+	return nil
+}
+
+func (p *Pool) done(j *job) {
+	if j.group != nil {
+		j.group.Done()
+	}
+	p.wg.Done()
+}
+
+// Wait
+// @Description: 等待所有已提交（包括任务内部提交）的任务结束
+// @receiver p
+func (p *Pool) Wait() {
+	p.wg.Wait()
+}
+
+// Go
+// @Description: 启动一个不占用槽位的协程（例如按轮次调度任务），Wait 同样会等待它结束
+// @receiver p
+// @param ctx
+// @param fn
+func (p *Pool) Go(ctx context.Context, fn func(ctx context.Context)) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		fn(ctx)
+	}()
+}
+
+// Group
+// @Description: 一组任务，可单独等待这一组结束
+type Group struct {
+	p  *Pool
+	wg sync.WaitGroup
+}
+
+// Group
+// @Description: 创建任务组
+// @receiver p
+// @return *Group
+func (p *Pool) Group() *Group {
+	return &Group{p: p}
+}
+
+// Submit
+// @Description: 向池中提交属于该组的任务，语义与 Pool.Submit 相同
+// @receiver g
+// @param ctx
+// @param host
+// @param fn
+func (g *Group) Submit(ctx context.Context, host string, fn func(ctx context.Context)) {
+	g.p.submit(ctx, host, fn, &g.wg)
+}
+
+// Wait
+// @Description: 等待该组任务结束，上下文取消时尚未执行的任务会被丢弃，不会一直阻塞
+// @receiver g
+func (g *Group) Wait() {
+	g.wg.Wait()
+}
+
+// HostOf
+// @Description: 提取URL中的主机（含端口），解析失败时返回原始字符串
+// @param rawURL
+// @return string
+func HostOf(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return strings.ToLower(u.Host)
+}
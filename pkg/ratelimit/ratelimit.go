@@ -0,0 +1,216 @@
+package ratelimit
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Limiter
+// @Description: 在所有任务间共享的登录频率限制：每个主机一个令牌桶，
+// 每次尝试前附加随机抖动，同一账号连续失败 N 次后冷却一段时间
+type Limiter struct {
+	interval      time.Duration // 每个令牌的产生间隔，0 表示不限速
+	jitter        time.Duration
+	cooldownAfter int
+	cooldown      time.Duration
+
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	failures map[string]int       // host + user -> 连续失败次数
+	cooling  map[string]time.Time // host + user -> 冷却结束时间
+}
+
+// bucket
+// @Description: 容量为 1 的令牌桶，next 为下一个令牌可用的时间
+type bucket struct {
+	next time.Time
+}
+
+// New
+// @Description: 初始化频率限制
+// @param perMinute 每个主机每分钟最多尝试次数，<=0 表示不限速
+// @param jitter 每次尝试前额外等待 [0, jitter) 的随机时长
+// @param cooldownAfter 同一账号连续失败多少次后冷却，<=0 表示不冷却
+// @param cooldown 冷却时长
+// @return *Limiter
+func New(perMinute int, jitter time.Duration, cooldownAfter int, cooldown time.Duration) *Limiter {
+	l := &Limiter{
+		jitter:        jitter,
+		cooldownAfter: cooldownAfter,
+		cooldown:      cooldown,
+		buckets:       make(map[string]*bucket),
+		failures:      make(map[string]int),
+		cooling:       make(map[string]time.Time),
+	}
+	if perMinute > 0 {
+		l.interval = time.Minute / time.Duration(perMinute)
+	}
+	return l
+}
+
+func accountKey(host, user string) string {
+	return host + "\x00" + user
+}
+
+// Wait
+// @Description: 等待该账号冷却结束并拿到该主机的令牌，可被上下文取消
+// @receiver l
+// @param ctx
+// @param host
+// @param user
+// @return error
+func (l *Limiter) Wait(ctx context.Context, host, user string) error {
+	if l == nil {
+		return nil
+	}
+
+	// 账号冷却
+	l.mu.Lock()
+	until := l.cooling[accountKey(host, user)]
+	l.mu.Unlock()
+	if d := time.Until(until); d > 0 {
+		log.WithFields(log.Fields{
+			"host":     host,
+			"username": user,
+			"wait":     d.Round(time.Second).String(),
+		}).Info("Account cooling down")
+		if err := sleep(ctx, d); err != nil {
+			return err
+		}
+	}
+
+	// 冷却期间令牌不应累积，所以在冷却结束后再预约令牌
+	slot := l.reserve(host)
+	d := time.Until(slot)
+	if l.jitter > 0 {
+		d += time.Duration(rand.Int63n(int64(l.jitter)))
+	}
+	if err := sleep(ctx, d); err != nil {
+		l.cancel(host, slot)
+		return err
+	}
+	return nil
+}
+
+// reserve
+// @Description: 预约该主机的下一个令牌，返回令牌可用的时间
+// @receiver l
+// @param host
+// @return time.Time
+func (l *Limiter) reserve(host string) time.Time {
+	now := time.Now()
+	if l.interval <= 0 {
+		return now
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[host]
+	if !ok {
+		b = &bucket{}
+		l.buckets[host] = b
+	}
+	slot := b.next
+	if slot.Before(now) {
+		slot = now
+	}
+	b.next = slot.Add(l.interval)
+	return slot
+}
+
+// cancel
+// @Description: 归还被取消的预约，避免后续任务多等一个间隔；之后已有其他预约时不归还
+// @receiver l
+// @param host
+// @param slot
+func (l *Limiter) cancel(host string, slot time.Time) {
+	if l.interval <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if b, ok := l.buckets[host]; ok && b.next.Equal(slot.Add(l.interval)) {
+		b.next = slot
+	}
+}
+
+// Failure
+// @Description: 记录该账号一次失败，连续失败达到阈值后开始冷却
+// @receiver l
+// @param host
+// @param user
+func (l *Limiter) Failure(host, user string) {
+	if l == nil || l.cooldownAfter <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := accountKey(host, user)
+	l.failures[key]++
+	if l.failures[key] >= l.cooldownAfter {
+		l.failures[key] = 0
+		l.cooling[key] = time.Now().Add(l.cooldown)
+	}
+}
+
+// Success
+// @Description: 登录成功，清空该账号的失败计数
+// @receiver l
+// @param host
+// @param user
+func (l *Limiter) Success(host, user string) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := accountKey(host, user)
+	delete(l.failures, key)
+	delete(l.cooling, key)
+}
+
+// Cooldown
+// @Description: 该账号剩余的冷却时长
+// @receiver l
+// @param host
+// @param user
+// @return time.Duration
+func (l *Limiter) Cooldown(host, user string) time.Duration {
+	if l == nil {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if d := time.Until(l.cooling[accountKey(host, user)]); d > 0 {
+		return d
+	}
+	return 0
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,213 @@
+package state
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"xiaoyu/pkg/utils"
+)
+
+// 记录类型
+const (
+	KindDetection = "detection"
+	KindAttempt   = "attempt"
+	KindSuccess   = "success"
+	KindProduct   = "product"
+)
+
+// Entry
+// @Description: 状态文件中的一行，key 为各字段的哈希，避免明文保存密码
+type Entry struct {
+	Kind string          `json:"kind"`
+	Key  string          `json:"key"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Store
+// @Description: 断点续跑的状态存储，追加写入并在每条记录后落盘。
+// nil *Store 可以安全调用，表示未启用断点续跑。
+type Store struct {
+	mu   sync.Mutex
+	file *os.File
+	done map[string]json.RawMessage
+}
+
+// Open
+// @Description: 打开状态文件
+// @param path 状态文件路径
+// @param resume 为 true 时加载已有记录，否则清空重新开始
+// @return *Store
+// @return error
+func Open(path string, resume bool) (*Store, error) {
+	s := &Store{done: make(map[string]json.RawMessage)}
+
+	flag := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if resume {
+		if err := s.load(path); err != nil {
+			return nil, err
+		}
+	} else {
+		flag |= os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(path, flag, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state file: %w", err)
+	}
+	if err = utils.TerminateLine(path, file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to repair state file: %w", err)
+	}
+	s.file = file
+	return s, nil
+}
+
+func (s *Store) load(path string) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		// 崩溃时最后一行可能不完整，直接跳过
+		if err = json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		s.done[e.Kind+":"+e.Key] = e.Data
+	}
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+	return nil
+}
+
+// Key
+// @Description: 计算记录的唯一标识
+// @param parts
+// @return string
+func Key(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Count
+// @Description: 已完成的记录数
+// @receiver s
+// @return int
+func (s *Store) Count() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.done)
+}
+
+// Done
+// @Description: 判断记录是否已完成
+// @receiver s
+// @param kind
+// @param parts
+// @return bool
+func (s *Store) Done(kind string, parts ...string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.done[kind+":"+Key(parts...)]
+	return ok
+}
+
+// Load
+// @Description: 读取已完成记录附带的数据
+// @receiver s
+// @param kind
+// @param v
+// @param parts
+// @return bool 记录存在且数据解析成功
+func (s *Store) Load(kind string, v interface{}, parts ...string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	data, ok := s.done[kind+":"+Key(parts...)]
+	s.mu.Unlock()
+	if !ok || len(data) == 0 {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
+
+// Mark
+// @Description: 标记记录已完成并立即落盘
+// @receiver s
+// @param kind
+// @param data 附带数据，可为 nil
+// @param parts
+// @return error
+func (s *Store) Mark(kind string, data interface{}, parts ...string) error {
+	if s == nil {
+		return nil
+	}
+
+	e := Entry{Kind: kind, Key: Key(parts...)}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("failed to encode state: %w", err)
+		}
+		e.Data = raw
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return fmt.Errorf("state file is closed")
+	}
+	if _, err = s.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	if err = s.file.Sync(); err != nil {
+		return fmt.Errorf("failed to flush state: %w", err)
+	}
+	s.done[e.Kind+":"+e.Key] = e.Data
+	return nil
+}
+
+// Close
+// @Description: 关闭状态文件
+// @receiver s
+// @return error
+func (s *Store) Close() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"io"
+	"os"
+)
+
+// TerminateLine
+// @Description: 上次异常退出留下半行时补一个换行，避免与新记录粘连，只读取文件最后一个字节
+// @param path 文件路径
+// @param file 以追加模式打开的同一文件
+// @return error
+func TerminateLine(path string, file *os.File) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil || size == 0 {
+		return err
+	}
+	last := make([]byte, 1)
+	if _, err = f.ReadAt(last, size-1); err != nil {
+		return err
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+	_, err = file.Write([]byte{'\n'})
+	return err
+}
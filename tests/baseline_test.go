@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"testing"
+
+	"xiaoyu/pkg/browser"
+)
+
+func Test_baseline_similarity(t *testing.T) {
+	failure := &browser.Snapshot{
+		URL:       "http://example.com/#/",
+		DOMHash:   "a",
+		Structure: map[string]int{"0:DIV": 1, "1:FORM": 1, "2:INPUT[text]": 1, "2:INPUT[password]": 1, "2:BUTTON": 1},
+		Text:      browser.TextShingles("XX管理系统 用户名 密码 记住我 登录 用户名或密码错误 Copyright 2024 XX科技"),
+		Cookies:   []string{"JSESSIONID"},
+	}
+
+	// 再次失败：只有提示文本略有不同
+	again := &browser.Snapshot{
+		URL:       "http://example.com/#/",
+		DOMHash:   "a",
+		Structure: failure.Structure,
+		Text:      browser.TextShingles("XX管理系统 用户名 密码 记住我 登录 用户名或密码错误，还可尝试4次 Copyright 2024 XX科技"),
+		Cookies:   []string{"JSESSIONID"},
+	}
+
+	// 成功：同一个 URL（单页应用），但页面内容完全不同
+	success := &browser.Snapshot{
+		URL:       "http://example.com/#/",
+		DOMHash:   "b",
+		Structure: map[string]int{"0:DIV": 1, "1:NAV": 1, "1:TABLE": 3},
+		Text:      browser.TextShingles("XX管理系统 首页 仪表盘 用户管理 今日访问 1024 退出登录"),
+		Cookies:   []string{"JSESSIONID", "token"},
+	}
+
+	if s := browser.Similarity(failure, failure); s != 1 {
+		t.Fatalf("identical snapshots should score 1, got %.2f", s)
+	}
+	same, diff := browser.Similarity(failure, again), browser.Similarity(failure, success)
+	if same < browser.DefaultBaselineThreshold {
+		t.Fatalf("repeated failure should stay close to the baseline, got %.2f", same)
+	}
+	if diff > 0.5 || diff >= same {
+		t.Fatalf("successful login should differ from the baseline, got %.2f (failure %.2f)", diff, same)
+	}
+}
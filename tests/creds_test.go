@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"xiaoyu/pkg/creds"
+)
+
+func Test_creds_list(t *testing.T) {
+	list, stats, err := creds.ReadList(strings.NewReader("\ufeffadmin\r\n\r\n# users\n  root  \nadmin\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(list, []string{"admin", "root"}) {
+		t.Fatalf("list = %q", list)
+	}
+	if stats.Kept != 2 || stats.Blank != 1 || stats.Comments != 1 || stats.Duplicates != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func Test_creds_combo(t *testing.T) {
+	pairs, stats, err := creds.ReadCombo(strings.NewReader("admin:p@ss:word\r\nroot:toor\nbroken\n:nouser\nadmin:\nroot:toor\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []creds.Pair{{Username: "admin", Password: "p@ss:word"}, {Username: "root", Password: "toor"}}
+	if !reflect.DeepEqual(pairs, want) {
+		t.Fatalf("pairs = %+v", pairs)
+	}
+	if stats.Invalid != 3 || stats.Duplicates != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func Test_creds_csv(t *testing.T) {
+	pairs, stats, err := creds.ReadCSV(strings.NewReader("id,Username, Password\n1,admin,admin123\n2,root,\"to,or\"\n3,admin,admin123\n4,guest\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []creds.Pair{{Username: "admin", Password: "admin123"}, {Username: "root", Password: "to,or"}}
+	if !reflect.DeepEqual(pairs, want) {
+		t.Fatalf("pairs = %+v", pairs)
+	}
+	if stats.Duplicates != 1 || stats.Invalid != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	if _, _, err = creds.ReadCSV(strings.NewReader("name,secret\nadmin,x\n")); !errors.Is(err, creds.ErrMissingColumn) {
+		t.Fatalf("expected missing column error, got %v", err)
+	}
+}
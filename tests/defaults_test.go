@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"xiaoyu/cmd"
+	"xiaoyu/pkg/config"
+	"xiaoyu/pkg/creds"
+)
+
+func Test_defaults_product_order(t *testing.T) {
+	d := creds.BuiltinDefaults()
+
+	got := d.Credentials("通达OA", nil)
+	if len(got) < 3 {
+		t.Fatalf("too few credentials: %v", got)
+	}
+	// 厂商默认账号排在最前
+	if got[0] != (creds.Pair{Username: "admin", Password: "admin"}) || got[1] != (creds.Pair{Username: "admin", Password: "admin123"}) {
+		t.Errorf("vendor pairs should come first: %v", got[:2])
+	}
+	seen := make(map[creds.Pair]bool)
+	for _, pair := range got {
+		if seen[pair] {
+			t.Errorf("duplicate credential %v", pair)
+		}
+		seen[pair] = true
+	}
+
+	// 未识别的产品只使用通用凭证，指定的用户优先于默认用户
+	generic := d.Credentials("unknown", []string{"root"})
+	if len(generic) == 0 || generic[0].Username != "root" || generic[0].Password != "123456" {
+		t.Errorf("unexpected generic credentials: %v", generic)
+	}
+}
+
+func Test_defaults_load_extend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "defaults.yaml")
+	data := `
+generic:
+  passwords: ["Welcome1"]
+products:
+  通达OA:
+    pairs: ["admin:tongda2024"]
+  Custom:
+    users: [ops]
+    passwords: ["%domain%!"]
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := creds.LoadDefaults(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pairs := d.Credentials("通达OA", nil)
+	if pairs[2] != (creds.Pair{Username: "admin", Password: "tongda2024"}) {
+		t.Errorf("extra pairs should follow built-in pairs: %v", pairs[:3])
+	}
+	if last := pairs[len(pairs)-1]; last.Password != "Welcome1" {
+		t.Errorf("extra generic passwords should be appended: %v", last)
+	}
+
+	// Credentials 保留占位符，替换与跳过在 CreateTasks 中进行，见 Test_defaults_skip_missing_placeholders
+	custom := d.Credentials("Custom", nil)
+	if custom[0] != (creds.Pair{Username: "ops", Password: "%domain%!"}) {
+		t.Errorf("unexpected custom credentials: %v", custom[:1])
+	}
+
+	if err := os.WriteFile(path, []byte("products:\n  X:\n    pairs: [\"nopass\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := creds.LoadDefaults(path); err == nil {
+		t.Error("invalid pair should fail")
+	}
+}
+
+func Test_defaults_skip_missing_placeholders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "defaults.yaml")
+	data := `
+products:
+  Custom:
+    pairs: ["root:%company%!"]
+    users: [ops]
+    passwords: ["%company%@%year%", "%domain%@%year%", "%User%1"]
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := creds.LoadDefaults(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 目标没有配置 company，引用它的默认凭证在生成任务时跳过，其余占位符按用户和目标替换
+	target := &config.Target{URL: "https://mail.example.com/", Users: []string{"ops"}}
+	var got []string
+	for _, task := range cmd.CreateTasks(config.NewConfig(), target, nil, d, "Custom") {
+		got = append(got, task.Username+":"+task.Password)
+	}
+	year := strconv.Itoa(time.Now().Year())
+	want := []string{"ops:example@" + year, "ops:Ops1"}
+	if !reflect.DeepEqual(got[:2], want) {
+		t.Errorf("CreateTasks = %q, want prefix %q", got, want)
+	}
+	for _, cred := range got {
+		if strings.Contains(cred, "%") || strings.HasPrefix(cred, "root:") {
+			t.Errorf("credential with a missing placeholder was kept: %s", cred)
+		}
+	}
+}
+
+func Test_defaults_tasks_expanded(t *testing.T) {
+	target := &config.Target{URL: "https://mail.example.com/", Company: "Acme"}
+	tasks := cmd.CreateTasks(config.NewConfig(), target, nil, creds.BuiltinDefaults(), "Coremail")
+	if len(tasks) == 0 {
+		t.Fatal("no default credentials")
+	}
+	for _, task := range tasks {
+		if strings.Contains(task.Password, "%") {
+			t.Errorf("placeholder left in %s / %s", task.Username, task.Password)
+		}
+	}
+}
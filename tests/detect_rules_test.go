@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"xiaoyu/pkg/browser"
+)
+
+func Test_detect_rules_default(t *testing.T) {
+	r := browser.DefaultDetectRules()
+	for _, field := range browser.SelectorFields {
+		patterns := r.Patterns(field)
+		if len(patterns) == 0 {
+			t.Errorf("%s has no patterns", field)
+			continue
+		}
+		for i := 1; i < len(patterns); i++ {
+			if patterns[i].Weight > patterns[i-1].Weight {
+				t.Errorf("%s patterns not ordered by weight: %v", field, patterns)
+				break
+			}
+		}
+	}
+	if got := r.Patterns(browser.FieldPasswordInput)[0].Selector; got != "input[type='password']" {
+		t.Errorf("strongest password pattern = %q", got)
+	}
+}
+
+func Test_detect_rules_load(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "detect.yaml")
+	data := `
+passwordInput:
+  common:
+    - { selector: "input[type='password']", weight: 0 }
+  ko:
+    - { selector: "input[placeholder*='비밀번호']", weight: 20 }
+loginBtn:
+  zh:
+    - "xpath:.//a[contains(., '登录')]"
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := browser.LoadDetectRules(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pass := r.Patterns(browser.FieldPasswordInput)
+	if pass[0].Selector != "input[placeholder*='비밀번호']" {
+		t.Errorf("new group should be ordered by weight: %v", pass[0])
+	}
+	for _, p := range pass {
+		if p.Selector == "input[type='password']" {
+			t.Error("weight 0 should disable the pattern")
+		}
+	}
+
+	btn := r.Patterns(browser.FieldLoginBtn)
+	if last := btn[len(btn)-1]; last.Selector != "xpath:.//a[contains(., '登录')]" || last.Weight != 1 {
+		t.Errorf("plain selector should default to weight 1: %v", last)
+	}
+
+	for _, bad := range []string{"captcha:\n  en: [\"img\"]\n", "userInput:\n  en: [\"text:Login\"]\n", "userInput:\n  en:\n    - { selector: \"input\", weight: -1 }\n"} {
+		if err := os.WriteFile(path, []byte(bad), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := browser.LoadDetectRules(path); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"xiaoyu/pkg/browser"
+	"xiaoyu/pkg/fingerprint"
+)
+
+func Test_fingerprint_murmur3(t *testing.T) {
+	cases := map[string]int32{
+		"":    0,
+		"foo": -156908512,
+	}
+	for input, want := range cases {
+		if got := fingerprint.Murmur3([]byte(input)); got != want {
+			t.Errorf("Murmur3(%q) = %d, want %d", input, got, want)
+		}
+	}
+}
+
+func Test_fingerprint_parse(t *testing.T) {
+	list, err := fingerprint.Parse([]byte(`
+- name: A
+  title: [a, b]
+- name: B
+  html: "<div id=b>"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || !reflect.DeepEqual([]string(list[0].Title), []string{"a", "b"}) || list[1].HTML[0] != "<div id=b>" {
+		t.Fatalf("unexpected products: %+v %+v", list[0], list[1])
+	}
+
+	single, err := fingerprint.Parse([]byte("name: C\nheaders:\n  Server: nginx\n"))
+	if err != nil || len(single) != 1 || single[0].Headers["Server"] != "nginx" {
+		t.Fatalf("unexpected single product: %v %v", single, err)
+	}
+
+	if _, err := fingerprint.Parse([]byte("- title: x\n")); err == nil {
+		t.Error("product without name should fail")
+	}
+}
+
+func Test_fingerprint_match(t *testing.T) {
+	data, err := os.ReadFile("../config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := fingerprint.ParseDB(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(db.Products) == 0 {
+		t.Fatal("no products in config.yaml")
+	}
+
+	// 三个产品共用同一个 indicate，标题命中的排在前面
+	f := &browser.PageFeatures{
+		Title:   "契约锁电子签章平台",
+		HTML:    `<div id="app" class="webapp-desktop"></div>`,
+		Headers: map[string]string{},
+	}
+	matches := db.Match(f)
+	if len(matches) < 2 {
+		t.Fatalf("expected several candidates, got %+v", matches)
+	}
+	if matches[0].Name != "契约锁-前台登陆页面" {
+		t.Errorf("best match = %s, want 契约锁-前台登陆页面", matches[0].Name)
+	}
+	if want := []string{`html:id="app" class="webapp-desktop"`, "title:契约锁"}; !reflect.DeepEqual(matches[0].Evidence, want) {
+		t.Errorf("evidence = %q, want %q", matches[0].Evidence, want)
+	}
+
+	if got := db.Match(&browser.PageFeatures{Title: "nothing"}); len(got) != 0 {
+		t.Errorf("unexpected matches: %+v", got)
+	}
+}
+
+func Test_fingerprint_load_override(t *testing.T) {
+	db, err := fingerprint.ParseDB([]byte("framework:\n  - name: A\n    title: old\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.yaml"), []byte("name: A\ntitle: new\nfavicon: [116323821]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "b.yml"), []byte("- name: B\n  headers:\n    X-Powered-By: php\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Load(dir); err != nil {
+		t.Fatal(err)
+	}
+	if len(db.Products) != 2 || db.Products[0].Title[0] != "new" || !db.NeedsFavicon() {
+		t.Fatalf("unexpected products after load: %+v", db.Products)
+	}
+
+	matches := db.Match(&browser.PageFeatures{Headers: map[string]string{"x-powered-by": "PHP/7.4"}})
+	if len(matches) != 1 || matches[0].Name != "B" || matches[0].Evidence[0] != "header:X-Powered-By=PHP/7.4" {
+		t.Errorf("unexpected header match: %+v", matches)
+	}
+}
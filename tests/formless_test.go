@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-rod/rod/lib/launcher"
+	"xiaoyu/pkg/browser"
+)
+
+// formlessPage 登录框不在 form 中（如 Vue/React 页面），页面上另有一个搜索表单
+const formlessPage = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>登录</title></head>
+<body>
+  <form role="search" action="/search">
+    <input type="search" name="q" placeholder="搜索">
+    <button type="submit">搜索</button>
+  </form>
+  <div class="login-box" style="width: 360px; margin: 80px auto;">
+    <input type="text" name="username" placeholder="用户名">
+    <input type="password" name="password" placeholder="密码">
+    <div class="login-btn" onclick="document.title = 'submitted'">登录</div>
+  </div>
+</body>
+</html>`
+
+func Test_formless_detect(t *testing.T) {
+	if _, found := launcher.LookPath(); !found {
+		t.Skip("no browser available")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, formlessPage)
+	}))
+	defer server.Close()
+
+	b, err := browser.New(true, "", "")
+	if err != nil {
+		t.Skipf("browser unavailable: %v", err)
+	}
+	defer b.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err = b.Navigate(ctx, server.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	forms, err := b.DetectForms(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	form, err := browser.SelectForm(forms, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 唯一的真实表单序号为 0，虚拟容器从 1 开始
+	if !form.Virtual || form.Index != 1 {
+		t.Fatalf("expected the virtual form at index 1, got virtual=%v index=%d", form.Virtual, form.Index)
+	}
+	if picked, err := browser.SelectForm(forms, 1); err != nil || picked != form {
+		t.Fatalf("form index 1 should select the virtual form: %v", err)
+	}
+
+	s := form.Selector()
+	if s.UserInput == "" || s.PasswordInput == "" || s.LoginBtn == "" {
+		t.Fatalf("incomplete selector: %+v", s)
+	}
+	for _, report := range b.ValidateSelector(ctx, s) {
+		if !report.OK {
+			t.Errorf("%s does not match exactly one visible element: %+v", report.Field, report.Candidates)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"xiaoyu/pkg/lockout"
+)
+
+func Test_lockout_detector(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "phrases.yaml")
+	if err := os.WriteFile(path, []byte("de:\n  - Konto gesperrt\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	catalog, err := lockout.LoadCatalog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := lockout.NewDetector(catalog)
+
+	cases := []struct {
+		o     lockout.Observation
+		fired bool
+	}{
+		{lockout.Observation{Text: "用户名或密码错误"}, false},
+		{lockout.Observation{Status: 429}, true},
+		{lockout.Observation{Status: 423}, true},
+		{lockout.Observation{Text: "Too many login attempts, try again later"}, true},
+		{lockout.Observation{Text: "账号已锁定"}, true},
+		{lockout.Observation{Text: "Ihr Konto gesperrt"}, true},
+		{lockout.Observation{CaptchaAppeared: true}, true},
+	}
+	for _, c := range cases {
+		if reason, fired := d.Check(c.o); fired != c.fired {
+			t.Errorf("Check(%+v) = %q, %v; want %v", c.o, reason, fired, c.fired)
+		}
+	}
+
+	if _, err := lockout.ParseAction("later"); err == nil {
+		t.Error("expected error for unknown action")
+	}
+}
+
+func Test_lockout_guard(t *testing.T) {
+	g := lockout.NewGuard(nil, lockout.ActionPause, 50*time.Millisecond, nil)
+	if a := g.Trip("h", "admin", "429"); a != lockout.ActionPause {
+		t.Fatalf("first trip = %s, want pause", a)
+	}
+	if _, blocked := g.Blocked("h", "admin"); blocked {
+		t.Fatal("paused user should not be blocked")
+	}
+	// 暂停期间其他并发尝试报告的锁定不升级
+	if a := g.Trip("h", "admin", "429"); a != lockout.ActionPause {
+		t.Fatalf("trip during pause = %s, want pause", a)
+	}
+
+	start := time.Now()
+	if err := g.WaitUntilClear(context.Background(), "h", "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Fatalf("WaitUntilClear returned after %v, want the pause", elapsed)
+	}
+	start = time.Now()
+	if err := g.WaitUntilClear(context.Background(), "h", "root"); err != nil || time.Since(start) > 10*time.Millisecond {
+		t.Fatal("other users should not wait")
+	}
+
+	if a := g.Trip("h", "admin", "429"); a != lockout.ActionSkipUser {
+		t.Fatalf("second trip = %s, want skip-user", a)
+	}
+	if _, blocked := g.Blocked("h", "admin"); !blocked {
+		t.Fatal("user should be blocked after second trip")
+	}
+	if _, blocked := g.Blocked("h", "root"); blocked {
+		t.Fatal("other users on the host should not be blocked")
+	}
+
+	g = lockout.NewGuard(nil, lockout.ActionSkipHost, 0, nil)
+	g.Trip("h", "admin", "423")
+	if _, blocked := g.Blocked("h", "root"); !blocked {
+		t.Fatal("host should be blocked for all users")
+	}
+
+	var aborted string
+	g = lockout.NewGuard(nil, lockout.ActionAbort, 0, func(reason string) { aborted = reason })
+	g.Trip("h", "admin", "captcha")
+	if aborted != "captcha" {
+		t.Fatalf("abort callback got %q", aborted)
+	}
+}
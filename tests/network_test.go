@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"testing"
+
+	"xiaoyu/pkg/browser"
+)
+
+func Test_network_verdict(t *testing.T) {
+	cases := []struct {
+		status  int
+		body    string
+		success bool
+		failed  bool
+	}{
+		{200, `{"code":0,"data":{"token":"abc"}}`, true, false},
+		{200, `{"code":401,"msg":"密码错误"}`, false, true},
+		{200, `{"code":"0"}`, true, false},
+		{200, `{"success":false}`, false, true},
+		{200, `{"status":"ok"}`, true, false},
+		{200, `{"status":"pending"}`, false, false},
+		{401, `Unauthorized`, false, true},
+		{302, ``, false, false},
+	}
+
+	for _, c := range cases {
+		v := browser.NetworkVerdict(&browser.Exchange{Method: "POST", URL: "http://example.com/api/login", Status: c.status, Body: c.body})
+		if v.Success != c.success || v.Failed != c.failed {
+			t.Fatalf("%d %s: unexpected verdict %+v", c.status, c.body, v)
+		}
+	}
+
+	if v := browser.NetworkVerdict(nil); v.Success || v.Failed {
+		t.Fatal("no exchange should give no verdict")
+	}
+}
+
+func Test_network_inconclusive_codes(t *testing.T) {
+	cases := []struct {
+		body    string
+		success bool
+		failed  bool
+	}{
+		{`{"status":1,"msg":"登录成功"}`, false, false},
+		{`{"ret":1}`, false, false},
+		{`{"code":500,"msg":"系统繁忙"}`, false, false},
+		{`{"code":403}`, false, true},
+		{`{"errcode":"401"}`, false, true},
+		{`{"code":"fail"}`, false, true},
+		{`{"ret":1,"code":0}`, true, false},
+	}
+
+	for _, c := range cases {
+		v := browser.NetworkVerdict(&browser.Exchange{Method: "POST", URL: "http://example.com/api/login", Status: 200, Body: c.body})
+		if v.Success != c.success || v.Failed != c.failed {
+			t.Errorf("%s: unexpected verdict %+v", c.body, v)
+		}
+	}
+}
+
+func Test_network_login_exchange(t *testing.T) {
+	analytics := &browser.Exchange{Method: "POST", URL: "http://example.com/collect", PostData: "event=click"}
+	captcha := &browser.Exchange{Method: "POST", URL: "http://example.com/captcha", PostData: "t=1"}
+	check := &browser.Exchange{Method: "GET", URL: "http://example.com/api/check?user=admin"}
+	login := &browser.Exchange{Method: "POST", URL: "http://example.com/api/login", PostData: `{"username":"admin","password":"P@ss w0rd"}`, AfterClick: true}
+	encrypted := &browser.Exchange{Method: "POST", URL: "http://example.com/api/login", PostData: "data=ZW5jcnlwdGVk", AfterClick: true}
+	beacon := &browser.Exchange{Method: "POST", URL: "http://example.com/collect", PostData: "event=submit", AfterClick: true}
+	byPassword := &browser.Exchange{Method: "POST", URL: "http://example.com/auth", PostData: "u=x&p=P%40ss+w0rd", AfterClick: true}
+
+	cases := []struct {
+		name      string
+		exchanges []*browser.Exchange
+		want      *browser.Exchange
+	}{
+		{"requests before the click are ignored", []*browser.Exchange{analytics, captcha, check}, nil},
+		{"request carrying the username", []*browser.Exchange{analytics, beacon, login}, login},
+		{"request carrying the encoded password", []*browser.Exchange{beacon, byPassword}, byPassword},
+		{"first request after the click", []*browser.Exchange{analytics, encrypted, beacon}, encrypted},
+	}
+	for _, c := range cases {
+		if got := browser.SelectLoginExchange(c.exchanges, "admin", "P@ss w0rd"); got != c.want {
+			t.Errorf("%s: got %+v, want %+v", c.name, got, c.want)
+		}
+	}
+}
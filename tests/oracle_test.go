@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+	"xiaoyu/pkg/browser"
+)
+
+func Test_oracle_indicators(t *testing.T) {
+	data := []byte(`userInput: "#user"
+passwordInput: "#pass"
+loginBtn: "#submit"
+indicatorMatch: any
+successIndicators:
+  - selector: ".logout"
+    type: element
+  - name: token-and-dashboard
+    all:
+      - {type: cookie, selector: auth_token}
+      - {type: url, selector: "/login", not: true}
+errorIndicators:
+  - {type: text, selector: "密码错误|invalid password"}
+  - {type: status, selector: "4xx,5xx"}
+`)
+	if errs := browser.ValidateSelectorData(data); len(errs) != 0 {
+		t.Fatalf("unexpected validation errors: %v", errs)
+	}
+
+	var s browser.Selector
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		t.Fatal(err)
+	}
+	o := browser.NewRuleOracle(&s)
+	if len(o.Success) != 2 || len(o.Error) != 2 || o.Match != browser.MatchAny {
+		t.Fatalf("unexpected oracle: %+v", o)
+	}
+	if got := o.Success[0].String(); got != "element:.logout" {
+		t.Fatalf("unexpected rule name: %s", got)
+	}
+	if got := o.Success[1].All[1].String(); got != "not url:/login" {
+		t.Fatalf("unexpected rule name: %s", got)
+	}
+
+	// 状态文件使用 JSON 保存选择器，规则需要保留
+	raw, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back browser.Selector
+	if err = json.Unmarshal(raw, &back); err != nil {
+		t.Fatal(err)
+	}
+	if len(back.SuccessIndicators) != 2 || back.SuccessIndicators[1].Name != "token-and-dashboard" {
+		t.Fatalf("indicators lost in JSON round trip: %s", raw)
+	}
+
+	// 未配置规则时使用默认判定
+	if o = browser.NewRuleOracle(&browser.Selector{}); len(o.Success) == 0 || len(o.Error) == 0 {
+		t.Fatal("default oracle should have rules")
+	}
+}
+
+func Test_oracle_invalid_rules(t *testing.T) {
+	data := []byte(`userInput: "#user"
+passwordInput: "#pass"
+loginBtn: "#submit"
+indicatorMatch: some
+successIndicators:
+  - {type: screenshot, selector: x}
+  - {type: status, selector: "2xxx"}
+  - {type: url, selector: "("}
+  - {type: element, selectr: ".logout"}
+`)
+	errs := browser.ValidateSelectorData(data)
+	lines := map[int]bool{}
+	for _, e := range errs {
+		lines[e.Line] = true
+	}
+	for _, line := range []int{4, 6, 7, 8, 9} {
+		if !lines[line] {
+			t.Fatalf("expected an error on line %d, got %v", line, errs)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"xiaoyu/pkg/browser"
+	"xiaoyu/pkg/crack"
+)
+
+func Test_outcome_classify(t *testing.T) {
+	rejected := fmt.Errorf("login failed: %w: element:.error", browser.ErrLoginRejected)
+	cases := []struct {
+		err  error
+		text string
+		code crack.Outcome
+		is   error
+	}{
+		{fmt.Errorf("%w: timed out", browser.ErrNavigationFailed), "", crack.OutcomeNavigationFailed, crack.ErrNavigationFailed},
+		{fmt.Errorf("%w: no visible form found", browser.ErrFormNotFound), "", crack.OutcomeFormNotFound, crack.ErrFormNotFound},
+		{fmt.Errorf("%w: user input", browser.ErrElementNotFound), "", crack.OutcomeElementMissing, crack.ErrElementMissing},
+		{fmt.Errorf("%w: OCR failed", browser.ErrCaptchaFailed), "", crack.OutcomeCaptchaFailed, crack.ErrCaptchaFailed},
+		{rejected, "用户名或密码错误", crack.OutcomeWrongCredentials, crack.ErrWrongCredentials},
+		{rejected, "账号已被锁定，请30分钟后再试", crack.OutcomeAccountLocked, crack.ErrAccountLocked},
+		{fmt.Errorf("%w after 10s", browser.ErrLoginTimeout), "Enter the code from your authenticator app", crack.OutcomeMFARequired, crack.ErrMFARequired},
+		{fmt.Errorf("%w after 10s", browser.ErrLoginTimeout), "Your password has expired", crack.OutcomePasswordExpired, crack.ErrPasswordExpired},
+		{fmt.Errorf("%w after 10s", browser.ErrLoginTimeout), "", crack.OutcomeTimeout, crack.ErrTimeout},
+		{errors.New("click failed"), "", crack.OutcomeUnknown, crack.ErrUnknown},
+	}
+
+	for _, c := range cases {
+		classified := crack.Classify(c.err, c.text)
+		if classified.Code != c.code {
+			t.Fatalf("%v: expected %s, got %s", c.err, c.code, classified.Code)
+		}
+		if !errors.Is(classified, c.is) || !errors.Is(classified, c.err) {
+			t.Fatalf("%v: classified error should wrap both the sentinel and the cause", c.err)
+		}
+	}
+
+	if crack.Classify(nil, "") != nil || crack.OutcomeOf(nil) != crack.OutcomeSuccess {
+		t.Fatal("nil error should be a success")
+	}
+}
+
+func Test_outcome_json(t *testing.T) {
+	classified := crack.Classify(fmt.Errorf("%w: element:.error", browser.ErrLoginRejected), "")
+	data, err := json.Marshal(crack.Result{
+		Code:    classified.Code,
+		Message: classified.Error(),
+		Error:   classified,
+		Task:    crack.Task{URL: "http://example.com", Username: "admin", Password: "admin"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]interface{}
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded["code"] != "wrong_credentials" || !strings.HasPrefix(decoded["message"].(string), "wrong username or password") {
+		t.Fatalf("unexpected serialized result: %s", data)
+	}
+	if _, ok := decoded["Error"]; ok {
+		t.Fatalf("raw error should not be serialized: %s", data)
+	}
+}
@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"xiaoyu/pkg/output"
+)
+
+func Test_output_writer_concurrent_and_merge(t *testing.T) {
+	dir := t.TempDir()
+	outputFile := filepath.Join(dir, "output.json")
+
+	// 第一轮模拟上一次运行遗留的记录，之后的续跑应追加而不是覆盖
+	for round := 0; round < 2; round++ {
+		w, err := output.New(outputFile, "", round > 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				kind := output.TypeAttempt
+				if i%10 == 0 {
+					kind = output.TypeSuccess
+				}
+				if err := w.Write(kind, "http://example.com", map[string]int{"i": i}); err != nil {
+					t.Error(err)
+				}
+			}(i)
+		}
+		wg.Wait()
+		if err = w.Write(output.TypeDetection, "http://example.com", nil); err != nil {
+			t.Fatal(err)
+		}
+		if err = w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report output.Report
+	if err = json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Attempts) != 90 || len(report.Successes) != 10 || len(report.Detections) != 2 {
+		t.Fatalf("unexpected report sizes: %d attempts, %d successes, %d detections",
+			len(report.Attempts), len(report.Successes), len(report.Detections))
+	}
+	if _, err = os.Stat(output.StreamFileFor(outputFile)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重新运行时清空上次的记录
+	w, err := output.New(outputFile, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Write(output.TypeDetection, "http://example.com", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if data, err = os.ReadFile(outputFile); err != nil {
+		t.Fatal(err)
+	}
+	report = output.Report{}
+	if err = json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Attempts) != 0 || len(report.Detections) != 1 {
+		t.Fatalf("fresh run kept earlier records: %d attempts, %d detections", len(report.Attempts), len(report.Detections))
+	}
+}
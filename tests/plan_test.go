@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"xiaoyu/pkg/crack"
+)
+
+func Test_plan_strategy(t *testing.T) {
+	var tasks []crack.Task
+	for _, user := range []string{"admin", "root", "test"} {
+		for _, pass := range []string{"123456", "admin123"} {
+			tasks = append(tasks, crack.Task{URL: "http://a", Username: user, Password: pass})
+		}
+	}
+
+	if rounds := crack.Plan(tasks, crack.StrategyUser); len(rounds) != 1 || len(rounds[0]) != 6 {
+		t.Fatalf("user strategy rounds = %v", rounds)
+	}
+
+	rounds := crack.Plan(tasks, crack.StrategySpray)
+	if len(rounds) != 2 {
+		t.Fatalf("spray rounds = %d, want 2", len(rounds))
+	}
+	for i, pass := range []string{"123456", "admin123"} {
+		if len(rounds[i]) != 3 {
+			t.Fatalf("round %d has %d tasks", i, len(rounds[i]))
+		}
+		for _, task := range rounds[i] {
+			if task.Password != pass {
+				t.Errorf("round %d has password %q, want %q", i, task.Password, pass)
+			}
+		}
+	}
+
+	if _, err := crack.ParseStrategy("random"); err == nil {
+		t.Error("expected error for unknown strategy")
+	}
+}
+
+func Test_plan_estimate(t *testing.T) {
+	rounds := [][]crack.Task{make([]crack.Task, 4), make([]crack.Task, 4)}
+
+	// 每轮 4 次、并发 2：2 * (5s + 1s)，两轮之间等待 60s
+	got := crack.Estimate(rounds, crack.Pacing{Concurrency: 2, Delay: time.Second, SprayWait: time.Minute})
+	if want := 2*12*time.Second + time.Minute; got != want {
+		t.Errorf("estimate = %s, want %s", got, want)
+	}
+
+	// 限速每分钟 2 次时，每轮至少 2 分钟
+	got = crack.Estimate(rounds, crack.Pacing{Concurrency: 2, Rate: 2})
+	if want := 4 * time.Minute; got != want {
+		t.Errorf("rate limited estimate = %s, want %s", got, want)
+	}
+}
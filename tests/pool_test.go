@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"xiaoyu/pkg/pool"
+)
+
+func Test_pool_limits(t *testing.T) {
+	p := pool.New(4, 2)
+
+	var mu sync.Mutex
+	var running, maxRunning int
+	hostRunning := map[string]int{}
+	maxHost := map[string]int{}
+	var done int32
+
+	for i := 0; i < 20; i++ {
+		host := []string{"a", "b", "c"}[i%3]
+		p.Submit(context.Background(), host, func(ctx context.Context) {
+			mu.Lock()
+			running++
+			hostRunning[host]++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			if hostRunning[host] > maxHost[host] {
+				maxHost[host] = hostRunning[host]
+			}
+			mu.Unlock()
+
+			time.Sleep(10 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			hostRunning[host]--
+			mu.Unlock()
+			atomic.AddInt32(&done, 1)
+		})
+	}
+	p.Wait()
+
+	if done != 20 {
+		t.Fatalf("expected 20 finished tasks, got %d", done)
+	}
+	if maxRunning > 4 {
+		t.Fatalf("global limit exceeded: %d", maxRunning)
+	}
+	for host, n := range maxHost {
+		if n > 2 {
+			t.Fatalf("host %s limit exceeded: %d", host, n)
+		}
+	}
+}
+
+func Test_pool_nested_submit_and_cancel(t *testing.T) {
+	p := pool.New(1, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var ran int32
+	p.Submit(ctx, "a", func(ctx context.Context) {
+		atomic.AddInt32(&ran, 1)
+		for i := 0; i < 5; i++ {
+			p.Submit(ctx, "a", func(ctx context.Context) {
+				atomic.AddInt32(&ran, 1)
+			})
+		}
+		cancel()
+	})
+	p.Wait()
+
+	if ran != 1 {
+		t.Fatalf("expected only the first task to run after cancel, got %d", ran)
+	}
+	if pool.HostOf("https://Example.com:8443/login") != "example.com:8443" {
+		t.Fatalf("unexpected host: %s", pool.HostOf("https://Example.com:8443/login"))
+	}
+}
+
+func Test_pool_group_rounds(t *testing.T) {
+	p := pool.New(2, 1)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var order []int
+	p.Go(ctx, func(ctx context.Context) {
+		for round := 0; round < 3; round++ {
+			round := round
+			g := p.Group()
+			for i := 0; i < 3; i++ {
+				g.Submit(ctx, "a", func(ctx context.Context) {
+					time.Sleep(time.Millisecond)
+					mu.Lock()
+					order = append(order, round)
+					mu.Unlock()
+				})
+			}
+			g.Wait()
+		}
+	})
+	p.Wait()
+
+	if len(order) != 9 {
+		t.Fatalf("expected 9 finished tasks, got %d", len(order))
+	}
+	for i, round := range order {
+		if round != i/3 {
+			t.Fatalf("rounds interleaved: %v", order)
+		}
+	}
+}
+
+func Test_pool_fifo(t *testing.T) {
+	p := pool.New(1, 1)
+	ctx := context.Background()
+
+	var order []int
+	for i := 0; i < 10; i++ {
+		i := i
+		p.Submit(ctx, "a", func(ctx context.Context) {
+			order = append(order, i)
+		})
+	}
+	p.Wait()
+
+	for i, n := range order {
+		if n != i {
+			t.Fatalf("tasks ran out of submission order: %v", order)
+		}
+	}
+	if len(order) != 10 {
+		t.Fatalf("expected 10 finished tasks, got %d", len(order))
+	}
+}
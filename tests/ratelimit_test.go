@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"xiaoyu/pkg/ratelimit"
+)
+
+func Test_ratelimit_host(t *testing.T) {
+	// 每分钟 1200 次，即每 50ms 一次
+	l := ratelimit.New(1200, 0, 0, 0)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := l.Wait(ctx, "a.example", "admin"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 140*time.Millisecond {
+		t.Fatalf("4 attempts took %s, expected at least 150ms", elapsed)
+	}
+
+	// 其他主机不受影响
+	start = time.Now()
+	if err := l.Wait(ctx, "b.example", "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Fatalf("first attempt on another host waited %s", elapsed)
+	}
+
+	// 取消等待
+	cancelled, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	l.Wait(ctx, "c.example", "admin")
+	if err := l.Wait(cancelled, "c.example", "admin"); err == nil {
+		t.Fatal("expected context error")
+	}
+}
+
+func Test_ratelimit_cooldown(t *testing.T) {
+	l := ratelimit.New(0, 0, 2, 100*time.Millisecond)
+
+	l.Failure("h", "admin")
+	if d := l.Cooldown("h", "admin"); d != 0 {
+		t.Fatalf("cooldown after one failure = %s", d)
+	}
+	l.Failure("h", "admin")
+	if d := l.Cooldown("h", "admin"); d <= 0 {
+		t.Fatal("expected cooldown after two failures")
+	}
+	if d := l.Cooldown("h", "root"); d != 0 {
+		t.Fatalf("other account cooling down for %s", d)
+	}
+
+	start := time.Now()
+	if err := l.Wait(context.Background(), "h", "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Fatalf("waited %s, expected the cooldown", elapsed)
+	}
+
+	l.Failure("h", "admin")
+	l.Success("h", "admin")
+	l.Failure("h", "admin")
+	if d := l.Cooldown("h", "admin"); d != 0 {
+		t.Fatal("success should reset the failure count")
+	}
+}
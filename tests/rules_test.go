@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"xiaoyu/cmd"
+	"xiaoyu/pkg/config"
+	"xiaoyu/pkg/crack"
+	"xiaoyu/pkg/creds"
+)
+
+func Test_rules_placeholders(t *testing.T) {
+	v := crack.VarsFor("https://mail.example.com.cn:8443/login", "admin", "Acme")
+	if v.Host != "mail.example.com.cn" || v.Domain != "example" {
+		t.Fatalf("unexpected vars: %+v", v)
+	}
+	v.Year = 2025
+
+	got := crack.Expand("%User%@%year%-%user%-%company%-%domain%-%host%", v)
+	if want := "Admin@2025-admin-Acme-example-mail.example.com.cn"; got != want {
+		t.Errorf("Expand = %q, want %q", got, want)
+	}
+	if crack.VarsFor("http://10.0.0.1:8080", "admin", "").Domain != "10.0.0.1" {
+		t.Error("ip host should be used as the domain")
+	}
+}
+
+func Test_rules_mutate(t *testing.T) {
+	rules, err := crack.ParseRules(strings.NewReader(`
+# comment
+:
+capitalize append:@%year%,123,@%company%
+leet reverse
+upper minlen:7
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := crack.Vars{User: "admin", Year: 2025}
+
+	got := crack.Mutate([]string{"password", "%user%"}, rules, v)
+	want := []string{
+		"password", "Password@2025", "Password123", "dr0w$$@p", "PASSWORD",
+		"admin", "Admin@2025", "Admin123", "n1md@",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Mutate = %q\nwant %q", got, want)
+	}
+
+	// 没有规则时只替换占位符并去重
+	if got := crack.Mutate([]string{"%user%", "admin", ""}, nil, v); !reflect.DeepEqual(got, []string{"admin"}) {
+		t.Errorf("Mutate without rules = %q", got)
+	}
+
+	for _, bad := range []string{"shout", "append:", "minlen:x", "upper:1"} {
+		if _, err := crack.ParseRule(bad); err == nil {
+			t.Errorf("expected error for rule %q", bad)
+		}
+	}
+}
+
+func Test_rules_scope(t *testing.T) {
+	rules, err := crack.ParseRules(strings.NewReader("append:123\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := &config.Target{
+		URL:       "http://example.com/login",
+		Users:     []string{"admin"},
+		Passwords: []string{"pass"},
+		Combos:    []creds.Pair{{Username: "root", Password: "%user%"}},
+	}
+
+	var got []string
+	for _, task := range cmd.CreateTasks(&config.Config{CrackAll: true}, target, rules, creds.BuiltinDefaults(), "") {
+		got = append(got, task.Username+":"+task.Password)
+	}
+	// 规则只作用于密码列表，组合文件中的凭证只替换占位符
+	want := []string{"admin:pass123", "root:root"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CreateTasks = %v, want %v", got, want)
+	}
+
+	target.Passwords, target.Combos = nil, nil
+	for _, task := range cmd.CreateTasks(&config.Config{CrackAll: true}, target, rules, creds.BuiltinDefaults(), "") {
+		if strings.HasSuffix(task.Password, "123123") {
+			t.Errorf("default credential %s:%s was mutated by rules", task.Username, task.Password)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"xiaoyu/pkg/browser"
+)
+
+// 首屏居中、尺寸正常的表单
+func visibleForm(f browser.FormFeatures) *browser.FormFeatures {
+	f.X, f.Y, f.Width, f.Height = 500, 200, 360, 280
+	f.ViewportWidth, f.ViewportHeight = 1366, 768
+	return &f
+}
+
+func Test_score_login_register_search(t *testing.T) {
+	login := browser.ScoreForm(visibleForm(browser.FormFeatures{
+		ID:      "loginForm",
+		Types:   []string{"text", "password", "checkbox"},
+		Buttons: []string{"登 录", "注册"},
+		Labels:  []string{"请输入账号", "请输入密码"},
+	}), map[string]int{
+		browser.FieldUserInput:     7,
+		browser.FieldPasswordInput: 10,
+		browser.FieldLoginBtn:      10,
+		browser.FieldRememberMe:    1,
+	})
+
+	register := browser.ScoreForm(visibleForm(browser.FormFeatures{
+		Types:   []string{"text", "email", "tel", "password", "password"},
+		Buttons: []string{"Sign up"},
+		Labels:  []string{"Username", "Email", "Phone", "Password", "Confirm password"},
+	}), map[string]int{
+		browser.FieldUserInput:     10,
+		browser.FieldPasswordInput: 10,
+		browser.FieldLoginBtn:      10,
+	})
+
+	search := browser.ScoreForm(visibleForm(browser.FormFeatures{
+		Role:    "search",
+		Types:   []string{"search"},
+		Buttons: []string{"搜索"},
+	}), map[string]int{
+		browser.FieldLoginBtn: 10,
+	})
+
+	if !(login.Score > register.Score && register.Score > search.Score) {
+		t.Fatalf("unexpected ranking: login=%d register=%d search=%d", login.Score, register.Score, search.Score)
+	}
+	if login.Confidence != 1 {
+		t.Errorf("login confidence = %v, reasons %v", login.Confidence, login.Reasons)
+	}
+	if search.Confidence != 0 {
+		t.Errorf("search confidence = %v, reasons %v", search.Confidence, search.Reasons)
+	}
+	if register.Confidence >= browser.LowConfidence {
+		t.Errorf("register confidence = %v, reasons %v", register.Confidence, register.Reasons)
+	}
+
+	// 有登录按钮时，旁边的注册按钮不扣分
+	for _, r := range login.Reasons {
+		if r == "register:注册-30" {
+			t.Errorf("login form penalised for register button: %v", login.Reasons)
+		}
+	}
+
+	hidden := browser.ScoreForm(&browser.FormFeatures{Types: []string{"password"}}, map[string]int{browser.FieldPasswordInput: 10})
+	if hidden.Score >= login.Score || hidden.Reasons[len(hidden.Reasons)-1] != "hidden-50" {
+		t.Errorf("hidden form not penalised: %v", hidden.Reasons)
+	}
+}
+
+func Test_score_select_form(t *testing.T) {
+	forms := []*browser.FormDesc{
+		{Index: 2, FormScore: browser.FormScore{Score: 90}},
+		{Index: 0, FormScore: browser.FormScore{Score: 60}, Complete: true},
+		{Index: 1, FormScore: browser.FormScore{Score: 10}, Complete: true},
+	}
+
+	if f, err := browser.SelectForm(forms, -1); err != nil || f.Index != 0 {
+		t.Errorf("best complete form = %+v, %v", f, err)
+	}
+	if f, err := browser.SelectForm(forms, 1); err != nil || f.Index != 1 {
+		t.Errorf("form by index = %+v, %v", f, err)
+	}
+	if _, err := browser.SelectForm(forms, 2); !errors.Is(err, browser.ErrFormNotFound) {
+		t.Errorf("incomplete form should not be selectable: %v", err)
+	}
+	if _, err := browser.SelectForm(forms, 5); !errors.Is(err, browser.ErrFormNotFound) {
+		t.Errorf("missing index should fail: %v", err)
+	}
+	if _, err := browser.SelectForm(nil, -1); !errors.Is(err, browser.ErrFormNotFound) {
+		t.Errorf("no forms should fail: %v", err)
+	}
+}
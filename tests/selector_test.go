@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+	"xiaoyu/pkg/browser"
+)
+
+func Test_parse_selector(t *testing.T) {
+	cases := []struct {
+		raw  string
+		kind browser.SelectorKind
+		val  string
+		name string
+	}{
+		{"input[name='username']", browser.KindCSS, "input[name='username']", ""},
+		{"//input[@id='uid']", browser.KindXPath, "//input[@id='uid']", ""},
+		{"(//button)[2]", browser.KindXPath, "(//button)[2]", ""},
+		{"css: #loginBtn", browser.KindCSS, "#loginBtn", ""},
+		{"xpath://*[@name='submit']", browser.KindXPath, "//*[@name='submit']", ""},
+		{"text:登录", browser.KindText, "登录", ""},
+		{"role:button", browser.KindRole, "button", ""},
+		{`role:Button[name="Sign in"]`, browser.KindRole, "button", "Sign in"},
+	}
+
+	for _, c := range cases {
+		q := browser.ParseSelector(c.raw)
+		if q.Kind != c.kind || q.Value != c.val || q.Name != c.name {
+			t.Errorf("ParseSelector(%q) = %+v, want %s %q %q", c.raw, q, c.kind, c.val, c.name)
+		}
+	}
+}
+
+func Test_selector_candidates(t *testing.T) {
+	var s browser.Selector
+	err := yaml.Unmarshal([]byte(`
+userInput:
+  - "//*[@id='uid']"
+  - "css:input[name='username']"
+passwordInput: "//input[@type='password']"
+loginBtn: ["text:登录", "role:button"]
+`), &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.UserInput != "//*[@id='uid']" || len(s.Candidates(browser.FieldUserInput)) != 2 {
+		t.Fatalf("unexpected userInput candidates: %q %v", s.UserInput, s.Candidates(browser.FieldUserInput))
+	}
+	if got := s.Candidates(browser.FieldPasswordInput); len(got) != 1 || got[0] != "//input[@type='password']" {
+		t.Fatalf("unexpected passwordInput candidates: %v", got)
+	}
+	if s.Candidates(browser.FieldCaptchaImg) != nil {
+		t.Fatal("empty field should have no candidates")
+	}
+
+	// JSON 往返（断点续跑的状态文件使用 JSON）保留候选列表
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back browser.Selector
+	if err = json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if got := back.Candidates(browser.FieldLoginBtn); len(got) != 2 || got[1] != "role:button" {
+		t.Fatalf("candidates lost in JSON round trip: %s", data)
+	}
+}
+
+func Test_selector_schema(t *testing.T) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal(browser.SelectorSchema, &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+
+	data := []byte("userInput: //input[@id='uid']\n" +
+		"passwordInput: 3\n" +
+		"loginBtn:\n" +
+		"  - \"\"\n" +
+		"timeout: {}\n")
+
+	got := map[string]int{}
+	for _, e := range browser.ValidateSelectorData(data) {
+		got[e.Field] = e.Line
+	}
+
+	// 未知字段同样是错误
+	if got[browser.FieldPasswordInput] != 2 || got[browser.FieldLoginBtn] != 4 || got["timeout"] != 5 || len(got) != 3 {
+		t.Fatalf("unexpected errors: %v", got)
+	}
+
+	// 已废弃的 timeouts 仍可加载
+	data = []byte("userInput: '#user'\npasswordInput: '#pass'\nloginBtn: '#submit'\ntimeouts:\n  login: 10\n  navigation: 5\n")
+	if errs := browser.ValidateSelectorData(data); len(errs) != 0 {
+		t.Fatalf("deprecated timeouts should be accepted: %v", errs)
+	}
+}
@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"xiaoyu/pkg/browser"
+	"xiaoyu/pkg/state"
+)
+
+func Test_state_resume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.jsonl")
+
+	store, err := state.Open(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Mark(state.KindAttempt, nil, "http://a", "admin", "123456"); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Mark(state.KindDetection, &browser.Selector{UserInput: "//input[1]"}, "http://a"); err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+
+	// 模拟崩溃时写了一半的行
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	_, _ = f.WriteString(`{"kind":"attempt","ke`)
+	_ = f.Close()
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "123456") {
+		t.Fatal("state file must not contain plaintext passwords")
+	}
+
+	store, err = state.Open(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if err = store.Mark(state.KindAttempt, nil, "http://a", "admin", "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if !store.Done(state.KindAttempt, "http://a", "admin", "123456") {
+		t.Fatal("attempt should be resumed")
+	}
+	if store.Done(state.KindAttempt, "http://a", "admin", "admin@123") {
+		t.Fatal("unexpected finished attempt")
+	}
+	var s browser.Selector
+	if !store.Load(state.KindDetection, &s, "http://a") || s.UserInput != "//input[1]" {
+		t.Fatalf("detection not resumed: %+v", s)
+	}
+
+	_ = store.Close()
+	store, err = state.Open(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !store.Done(state.KindAttempt, "http://a", "admin", "admin") {
+		t.Fatal("record written after a partial line should survive")
+	}
+
+	var nilStore *state.Store
+	if nilStore.Done(state.KindAttempt, "x") || nilStore.Mark(state.KindAttempt, nil, "x") != nil {
+		t.Fatal("nil store must be a no-op")
+	}
+}
@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"testing"
+
+	"xiaoyu/pkg/crack"
+)
+
+func Test_stop_conditions(t *testing.T) {
+	var stops []crack.Stop
+	s := crack.NewStopper(crack.StopPolicy{
+		MaxPerTarget: 5,
+		MaxPerUser:   2,
+		On:           crack.StopOnUser,
+	}, func(stop crack.Stop) { stops = append(stops, stop) })
+
+	task := func(user string) crack.Task {
+		return crack.Task{URL: "http://a", Username: user}
+	}
+
+	// 每个用户最多 2 次
+	for i := 0; i < 2; i++ {
+		if _, ok := s.Allow(task("admin")); !ok {
+			t.Fatalf("attempt %d for admin denied", i+1)
+		}
+	}
+	if reason, ok := s.Allow(task("admin")); ok || reason != crack.StopMaxUserCrackNum {
+		t.Fatalf("third attempt for admin = %q, %v", reason, ok)
+	}
+
+	// 成功后停止该用户
+	if _, ok := s.Allow(task("root")); !ok {
+		t.Fatal("attempt for root denied")
+	}
+	s.Record(crack.Result{Success: true, Task: task("root")})
+	if reason, stopped := s.Stopped(task("root")); !stopped || reason != crack.StopSuccess {
+		t.Fatalf("root after success = %q, %v", reason, stopped)
+	}
+
+	// 目标最多 5 次
+	for i := 0; i < 2; i++ {
+		if _, ok := s.Allow(task("test")); !ok {
+			t.Fatalf("attempt %d for test denied", i+1)
+		}
+	}
+	if reason, ok := s.Allow(task("guest")); ok || reason != crack.StopMaxCrackNum {
+		t.Fatalf("sixth attempt on target = %q, %v", reason, ok)
+	}
+
+	if stop := s.Finish("http://a", false); stop.Reason != crack.StopMaxCrackNum || stop.Attempts != 5 {
+		t.Fatalf("finish = %+v", stop)
+	}
+	if len(stops) != 3 {
+		t.Fatalf("expected 3 stop events, got %+v", stops)
+	}
+	if s.Finish("http://b", false).Reason != crack.StopCompleted {
+		t.Fatal("untouched target should complete")
+	}
+}
+
+func Test_stop_global_success_cap(t *testing.T) {
+	s := crack.NewStopper(crack.StopPolicy{On: crack.StopOnNever, MaxSuccesses: 2}, nil)
+
+	a := crack.Task{URL: "http://a", Username: "admin"}
+	b := crack.Task{URL: "http://b", Username: "admin"}
+	s.Record(crack.Result{Success: true, Task: a})
+	if _, ok := s.Allow(a); !ok {
+		t.Fatal("stop-on never should keep going after one success")
+	}
+	s.Record(crack.Result{Success: true, Task: a})
+	if reason, ok := s.Allow(b); ok || reason != crack.StopMaxSuccess {
+		t.Fatalf("other target after cap = %q, %v", reason, ok)
+	}
+	if s.Finish("http://b", false).Reason != crack.StopMaxSuccess {
+		t.Fatal("target should report the global cap")
+	}
+	if _, err := crack.ParseStopOn("always"); err == nil {
+		t.Error("expected error for unknown stop-on")
+	}
+}